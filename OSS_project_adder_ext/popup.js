@@ -3,6 +3,7 @@ document.addEventListener('DOMContentLoaded', function() {
 
     // Initialize favicon variables
     let currentFaviconData = null;
+    let currentFaviconSource = '';
     const faviconContainer = document.getElementById('faviconContainer');
     const faviconPreview = document.getElementById('faviconPreview');
     const faviconOverlay = document.getElementById('faviconOverlay');
@@ -52,53 +53,47 @@ document.addEventListener('DOMContentLoaded', function() {
         const websiteUrl = websiteInput.value.trim();
         if (websiteUrl) {
             fetchFavicon(websiteUrl);
+            draftProject(websiteUrl);
         }
     });
 
-    chrome.storage.local.get(['persistentFiles', 'completedFiles'], function(result) {
-        if (result.persistentFiles) {
-            const filesList = document.getElementById('addedFilesList');
-            filesList.innerHTML = '';
-            result.persistentFiles.forEach(file => {
-                const li = document.createElement('li');
-                li.style.display = 'flex';
-                li.style.alignItems = 'center';
-                li.style.marginBottom = '8px';
-                
-                const checkbox = document.createElement('input');
-                checkbox.type = 'checkbox';
-                checkbox.checked = result.completedFiles && result.completedFiles.includes(file);
-                checkbox.style.marginRight = '8px';
-                checkbox.addEventListener('change', (e) => {
-                    chrome.storage.local.get(['completedFiles'], function(storage) {
-                        const completed = new Set(storage.completedFiles || []);
-                        if (e.target.checked) {
-                            completed.add(file);
-                        } else {
-                            completed.delete(file);
-                        }
-                        chrome.storage.local.set({ 'completedFiles': Array.from(completed) });
-                    });
-                });
-                
-                const a = document.createElement('a');
-                a.href = '#';
-                a.textContent = file;
-                if (result.completedFiles && result.completedFiles.includes(file)) {
-                    a.style.textDecoration = 'line-through';
-                    a.style.color = '#666';
+    // Pre-fill empty form fields from the website's metadata
+    function draftProject(url) {
+        fetch(`http://localhost:8080/draftProject?url=${encodeURIComponent(url)}`)
+            .then(response => response.json())
+            .then(data => {
+                if (data.error || !data.project) {
+                    console.error('Error drafting project:', data.error);
+                    return;
                 }
-                a.addEventListener('click', (e) => {
-                    e.preventDefault();
-                    fetchFileContent(file);
+
+                const project = data.project;
+                const social = project.social || {};
+                const fields = {
+                    name: project.name,
+                    displayName: project.displayName,
+                    description: project.description,
+                    github: project.github && project.github[0].url,
+                    twitter: social.twitter && social.twitter[0].url,
+                    telegram: social.telegram && social.telegram[0].url,
+                    mirror: social.mirror && social.mirror[0].url,
+                    discord: social.discord && social.discord[0].url,
+                };
+                Object.entries(fields).forEach(([id, value]) => {
+                    const input = document.getElementById(id);
+                    if (value && !input.value) {
+                        input.value = value;
+                    }
                 });
-                
-                li.appendChild(checkbox);
-                li.appendChild(a);
-                filesList.appendChild(li);
+
+                if (data.duplicates && data.duplicates.length > 0) {
+                    console.warn('Possible duplicates:', data.duplicates);
+                }
+            })
+            .catch(error => {
+                console.error('Error drafting project:', error);
             });
-        }
-    });
+    }
 
     // Fetch favicon from a URL
     function fetchFavicon(url) {
@@ -113,6 +108,7 @@ document.addEventListener('DOMContentLoaded', function() {
                 if (!response.ok) {
                     throw new Error(`Failed to fetch favicon: ${response.status}`);
                 }
+                currentFaviconSource = response.headers.get('X-Favicon-Source') || '';
                 return response.blob();
             })
             .then(blob => {
@@ -128,6 +124,7 @@ document.addEventListener('DOMContentLoaded', function() {
                 console.error('Error fetching favicon:', error);
                 faviconContainer.style.display = 'none';
                 currentFaviconData = null;
+                currentFaviconSource = '';
             })
             .finally(() => {
                 faviconContainer.classList.remove('loading');
@@ -147,6 +144,7 @@ document.addEventListener('DOMContentLoaded', function() {
     // Remove favicon
     function removeFavicon() {
         currentFaviconData = null;
+        currentFaviconSource = '';
         faviconPreview.src = '';
         faviconContainer.style.display = 'none';
     }
@@ -231,8 +229,15 @@ document.addEventListener('DOMContentLoaded', function() {
             delete projectData.social;
         }
 
-        // Send project data to API
-        fetch('http://localhost:8080/createProject', {
+        createProject(projectData, false);
+    });
+
+    // Send project data to API, asking the user to confirm when possible duplicates exist
+    function createProject(projectData, force) {
+        const projectName = projectData.name;
+        const url = 'http://localhost:8080/createProject' + (force ? '?force=true' : '');
+
+        fetch(url, {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
@@ -241,6 +246,24 @@ document.addEventListener('DOMContentLoaded', function() {
         })
         .then(response => response.json())
         .then(data => {
+            if (data.duplicates && data.duplicates.length > 0) {
+                const candidates = data.duplicates
+                    .map(d => `- ${d.name}: ${d.reasons.join(', ')}`)
+                    .join('\n');
+                if (confirm(`"${projectName}" may already be in the directory:\n\n${candidates}\n\nCreate it anyway?`)) {
+                    createProject(projectData, true);
+                }
+                return;
+            }
+
+            if (data.error) {
+                const details = (data.validationErrors || [])
+                    .map(e => `- ${e.field}: ${e.message}`)
+                    .join('\n');
+                alert(`Error creating project: ${data.error}${details ? '\n\n' + details : ''}`);
+                return;
+            }
+
             console.log('Success:', data);
             
             // If the API doesn't automatically handle the favicon, manually save it
@@ -262,7 +285,7 @@ document.addEventListener('DOMContentLoaded', function() {
             console.error('Error:', error);
             alert(`Error creating project: ${error.message}`);
         });
-    });
+    }
 
     // Save favicon for a project
     function saveFavicon(projectName, faviconData) {
@@ -283,7 +306,7 @@ document.addEventListener('DOMContentLoaded', function() {
         const formData = new FormData();
         formData.append('favicon', blob);
         
-        fetch(`http://localhost:8080/saveFavicon?projectName=${encodeURIComponent(projectName)}`, {
+        fetch(`http://localhost:8080/saveFavicon?projectName=${encodeURIComponent(projectName)}&sourceUrl=${encodeURIComponent(currentFaviconSource)}`, {
             method: 'POST',
             body: blob
         })
@@ -367,8 +390,6 @@ document.addEventListener('DOMContentLoaded', function() {
                 }
                 filesList.innerHTML = '';
                 
-                chrome.storage.local.set({ 'persistentFiles': data.files });
-                
                 data.files.forEach(file => {
                     const li = document.createElement('li');
                     li.style.cssText = `
@@ -472,8 +493,8 @@ document.addEventListener('DOMContentLoaded', function() {
                     
                     resetBtn.addEventListener('click', function() {
                         if (confirm('Are you sure you want to reset the file list? This will remove all files and their checkmarks.')) {
-                            // Clear local storage
-                            chrome.storage.local.remove(['persistentFiles', 'completedFiles'], function() {
+                            // Clear the checkmarks
+                            chrome.storage.local.remove(['completedFiles'], function() {
                                 console.log('File list data cleared');
                                 
                                 // Clear the displayed list
@@ -512,6 +533,19 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     }
     
+    // Show the latest file the server recorded for this session
+    function updateLatestFile() {
+        fetch('http://localhost:8080/getLatestFile')
+        .then(response => response.json())
+        .then(data => {
+            if (data.latestFile) {
+                document.getElementById('latestFileName').textContent = data.latestFile;
+                document.getElementById('latestFileLog').style.display = 'block';
+            }
+        })
+        .catch(error => console.error('Error fetching latest file:', error));
+    }
+    
     function fetchFileContent(filename) {
         fetch(`http://localhost:8080/getFileContent?filename=${encodeURIComponent(filename)}`)
         .then(response => response.text())
@@ -549,7 +583,11 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     }
 
+    // The session's files come from the server; drop the copy older versions kept
+    chrome.storage.local.remove('persistentFiles');
+
     updateCurrentBranch();
+    updateLatestFile();
     updateAddedFilesList();
 
     chrome.storage.sync.get(['project'], function(result) {
@@ -593,38 +631,92 @@ document.addEventListener('DOMContentLoaded', function() {
     });
 
     document.getElementById('gitAddBtn').addEventListener('click', function() {
-        runGitCommand('git add --all');
+        runGitCommand({ operation: 'add' });
     });
 
     document.getElementById('gitCommitBtn').addEventListener('click', function() {
-        const commitMessage = prompt("Enter commit message:", "Add new project");
-        if (commitMessage) {
-            runGitCommand(`git commit -m "${commitMessage}"`);
-        }
+        // Preview the generated message so it can be confirmed or edited
+        fetch('http://localhost:8080/commitSession')
+        .then(response => response.json())
+        .then(preview => {
+            if (preview.error) {
+                alert('Nothing to commit: ' + preview.error);
+                return;
+            }
+            const subject = prompt("Commit message:", preview.subject);
+            if (subject) {
+                commitSession({ subject: subject, body: true });
+            }
+        })
+        .catch((error) => {
+            console.error('Error previewing commit:', error);
+            alert('Error previewing commit: ' + error.message);
+        });
     });
 
     document.getElementById('gitPullBtn').addEventListener('click', function() {
-        runGitCommand('git pull origin main --rebase');
+        runGitCommand({ operation: 'pull' });
     });
 
     document.getElementById('gitPushBtn').addEventListener('click', function() {
-        runGitCommand('git push origin main');
+        runGitCommand({ operation: 'push' });
     });
 
-    function runGitCommand(command) {
-        console.log(`Executing git command: ${command}`);
+    document.getElementById('openPullRequestBtn').addEventListener('click', function() {
+        fetch('http://localhost:8080/createPullRequest', { method: 'POST' })
+        .then(response => response.json())
+        .then(data => {
+            console.log('Pull request output:', data);
+            if (data.error) {
+                alert('Error opening pull request: ' + data.error);
+            } else {
+                chrome.tabs.create({ url: data.pullRequest.url });
+            }
+        })
+        .catch((error) => {
+            console.error('Error opening pull request:', error);
+            alert('Error opening pull request: ' + error.message);
+        });
+    });
+
+    function commitSession(request) {
+        fetch('http://localhost:8080/commitSession', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify(request)
+        })
+        .then(response => response.json())
+        .then(data => {
+            console.log('Commit output:', data);
+            if (data.error) {
+                alert('Error committing changes: ' + data.error);
+            } else {
+                alert('Committed ' + data.files.length + ' file(s): ' + data.subject);
+            }
+        })
+        .catch((error) => {
+            console.error('Error committing changes:', error);
+            alert('Error committing changes: ' + error.message);
+        });
+    }
+
+    function runGitCommand(request) {
+        console.log(`Executing git operation: ${request.operation}`);
         fetch('http://localhost:8080/runGitCommand', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json'
             },
-            body: JSON.stringify({ cmd: command })
+            body: JSON.stringify(request)
         })
         .then(response => response.json())
         .then(data => {
             console.log('Git command output:', data);
             if (data.error) {
-                alert('Error executing git command: ' + data.error);
+                const details = data.git && data.git.stderr ? '\n\n' + data.git.stderr : '';
+                alert('Error executing git command: ' + data.error + details);
             } else {
                 alert('Git command executed successfully: ' + data.message);
             }
@@ -0,0 +1,573 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Import formats
+const (
+	importFormatCSV   = "csv"
+	importFormatJSONL = "jsonl"
+)
+
+// maxImportBytes caps the size of an uploaded import file
+const maxImportBytes = 10 << 20
+
+// Outcomes of importing one row
+const (
+	importCreated   = "created"   // The project file was written
+	importValid     = "valid"     // Dry run: the project file would be written
+	importInvalid   = "invalid"   // The row could not be parsed or failed validation
+	importExists    = "exists"    // A project with this name already exists
+	importDuplicate = "duplicate" // The project looks like an existing project or an earlier row
+	importFailed    = "failed"    // The project file could not be written
+)
+
+// Column names accepted for project fields besides the YAML keys themselves
+var importColumnAliases = map[string]string{
+	"displayname": "display_name",
+	"title":       "display_name",
+	"website":     "websites",
+	"url":         "websites",
+	"homepage":    "websites",
+	"farcaster":   "social.farcaster",
+	"medium":      "social.medium",
+	"mirror":      "social.mirror",
+	"telegram":    "social.telegram",
+	"twitter":     "social.twitter",
+	"x":           "social.twitter",
+	"discord":     "social.discord",
+	"youtube":     "social.youtube",
+	"linkedin":    "social.linkedin",
+}
+
+// ImportOptions controls a bulk import
+type ImportOptions struct {
+	DryRun bool // Validate and report without writing anything
+	Force  bool // Import rows even if they look like existing projects
+}
+
+// ImportRow is one parsed row of an import file
+type ImportRow struct {
+	Line    int // Line of the row in the file
+	Project *Project
+	Err     error // Set when the row could not be parsed
+}
+
+// ImportRowResult reports what happened to one row
+type ImportRowResult struct {
+	Line             int                  `json:"line"`
+	Name             string               `json:"name,omitempty"`
+	Status           string               `json:"status"`
+	Path             string               `json:"path,omitempty"`
+	Message          string               `json:"message,omitempty"`
+	ValidationErrors []ValidationError    `json:"validationErrors,omitempty"`
+	Duplicates       []DuplicateCandidate `json:"duplicates,omitempty"`
+	URLChanges       []URLChange          `json:"urlChanges,omitempty"`
+}
+
+// ImportReport is the per-row result of a bulk import
+type ImportReport struct {
+	DryRun         bool              `json:"dryRun"`
+	Counts         map[string]int    `json:"counts"`
+	IgnoredColumns []string          `json:"ignoredColumns,omitempty"`
+	Rows           []ImportRowResult `json:"rows"`
+	StagedFiles    []string          `json:"stagedFiles,omitempty"`
+}
+
+// normalizeColumn turns a CSV header like "Display Name" into "display_name"
+func normalizeColumn(header string) string {
+	column := strings.ToLower(strings.TrimSpace(header))
+	column = strings.NewReplacer(" ", "_", "-", "_").Replace(column)
+	if field, ok := importColumnAliases[column]; ok {
+		return field
+	}
+	return column
+}
+
+// importField tells whether field is a project field rows can set
+func importField(field string) bool {
+	switch field {
+	case "name", "display_name", "description", "comments":
+		return true
+	}
+	for _, f := range projectURLFields(&Project{Social: &Social{}}) {
+		if f.name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// splitImportValues splits a cell holding several URLs or comments
+func splitImportValues(value string, separators string) []string {
+	var values []string
+	for _, v := range strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(separators, r) }) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// setImportField stores a cell value in the project field it is mapped to
+func setImportField(project *Project, field, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+
+	switch field {
+	case "name":
+		project.Name = value
+		return
+	case "display_name":
+		project.DisplayName = value
+		return
+	case "description":
+		project.Description = value
+		return
+	case "comments":
+		project.Comments = append(project.Comments, splitImportValues(value, "\n")...)
+		return
+	}
+
+	if project.Social == nil {
+		project.Social = &Social{}
+	}
+	for _, f := range projectURLFields(project) {
+		if f.name == field {
+			for _, u := range splitImportValues(value, " \t\n,;|") {
+				*f.ref = append(*f.ref, URL{Url: u})
+			}
+			return
+		}
+	}
+}
+
+// ParseImportCSV reads projects from a CSV file with a header row. mapping maps
+// column headers to project fields (e.g. "Project" to "display_name"); other
+// columns are matched by name, and the columns that match no field are returned.
+func ParseImportCSV(r io.Reader, mapping map[string]string) ([]ImportRow, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading CSV header: %v", err)
+	}
+
+	fields := make([]string, len(header))
+	var ignored []string
+	for i, column := range header {
+		field, ok := mapping[strings.TrimSpace(column)]
+		if !ok {
+			field = normalizeColumn(column)
+		}
+		if field != "" && field != "-" && !importField(field) {
+			if ok {
+				return nil, nil, fmt.Errorf("column %q is mapped to unknown field %q", column, field)
+			}
+			field = ""
+		}
+		if field == "" || field == "-" {
+			ignored = append(ignored, column)
+			field = ""
+		}
+		fields[i] = field
+	}
+
+	// A mapping for a column the file does not have is most likely a typo
+	columns := make(map[string]bool, len(header))
+	for _, column := range header {
+		columns[strings.TrimSpace(column)] = true
+	}
+	var missing []string
+	for column := range mapping {
+		if !columns[column] {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, nil, fmt.Errorf("mapped column %q is not in the CSV header", missing[0])
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, ImportRow{Line: parseErr.StartLine, Err: err})
+				continue
+			}
+			return nil, nil, fmt.Errorf("error reading CSV: %v", err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue // Spreadsheets often export trailing empty rows
+		}
+		line, _ := reader.FieldPos(0)
+
+		project := &Project{}
+		for i, value := range record {
+			if i < len(fields) && fields[i] != "" {
+				setImportField(project, fields[i], value)
+			}
+		}
+		if social := project.Social; social != nil && len(social.Farcaster)+len(social.Medium)+len(social.Mirror)+
+			len(social.Telegram)+len(social.Twitter)+len(social.Discord)+len(social.Youtube)+len(social.Linkedin) == 0 {
+			project.Social = nil
+		}
+		rows = append(rows, ImportRow{Line: line, Project: project})
+	}
+
+	return rows, ignored, nil
+}
+
+// ParseImportJSONL reads one JSON project, as sent to /createProject, per line
+func ParseImportJSONL(r io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxImportBytes)
+
+	var rows []ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var project Project
+		if err := json.Unmarshal([]byte(text), &project); err != nil {
+			rows = append(rows, ImportRow{Line: line, Err: fmt.Errorf("error decoding JSON: %v", err)})
+			continue
+		}
+		rows = append(rows, ImportRow{Line: line, Project: &project})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading JSONL: %v", err)
+	}
+	return rows, nil
+}
+
+// ImportProjects runs every row through the same normalization, validation and
+// duplicate detection as /createProject and writes the projects that pass. Rows are
+// also checked against the rows before them. Written files are staged together.
+func (s *Server) ImportProjects(rows []ImportRow, opts ImportOptions) ImportReport {
+	report := ImportReport{
+		DryRun: opts.DryRun,
+		Counts: make(map[string]int),
+		Rows:   []ImportRowResult{},
+	}
+	batch := NewProjectIndex(s.config.DirectoryPath)
+	var written []string
+
+	for _, row := range rows {
+		result := s.importRow(row, batch, opts)
+		if result.Status == importCreated {
+			written = append(written, result.Name)
+		}
+		report.Counts[result.Status]++
+		report.Rows = append(report.Rows, result)
+	}
+
+	if len(written) == 0 {
+		return report
+	}
+
+	paths := make([]string, len(written))
+	for i, name := range written {
+		paths[i] = projectRelPath(name)
+		s.refreshIndex(name)
+		s.recordFile(name+".yaml", actionCreated)
+	}
+	if err := s.stageFiles(paths...); err != nil {
+		log.Printf("Warning: Failed to stage imported projects: %v", err)
+	}
+	report.StagedFiles = s.session.StagedFiles()
+
+	return report
+}
+
+// importRow imports a single row, adding it to the batch index when it is accepted
+func (s *Server) importRow(row ImportRow, batch *ProjectIndex, opts ImportOptions) ImportRowResult {
+	result := ImportRowResult{Line: row.Line}
+	if row.Err != nil {
+		result.Status = importInvalid
+		result.Message = row.Err.Error()
+		return result
+	}
+
+	project := row.Project
+	if project.Name == "" && project.DisplayName != "" {
+		project.Name = GenerateSlug(project.DisplayName)
+	}
+	result.Name = project.Name
+
+	data, urlChanges, problems, err := s.prepareProject(project)
+	result.URLChanges = urlChanges
+	if len(problems) > 0 {
+		result.Status = importInvalid
+		result.Message = "Project failed validation"
+		result.ValidationErrors = problems
+		return result
+	}
+	if err != nil {
+		result.Status = importFailed
+		result.Message = err.Error()
+		return result
+	}
+
+	filePath, err := s.projectPath(project.Name)
+	if err != nil {
+		result.Status = importInvalid
+		result.Message = err.Error()
+		return result
+	}
+	result.Path = projectRelPath(project.Name)
+
+	// Checked before the file, which an earlier row may have written, so a dry run
+	// reports the same as the import
+	if _, ok := batch.Get(project.Name); ok {
+		result.Status = importDuplicate
+		result.Message = "An earlier row has the same name"
+		return result
+	}
+
+	if _, err := os.Stat(filePath); err == nil {
+		result.Status = importExists
+		result.Message = fmt.Sprintf("File %s already exists", result.Path)
+		return result
+	}
+	if !opts.Force {
+		duplicates := append(s.index.FindDuplicates(project), batch.FindDuplicates(project)...)
+		if len(duplicates) > 0 {
+			result.Status = importDuplicate
+			result.Message = "Possible duplicate projects found; import with force to create anyway"
+			result.Duplicates = duplicates
+			return result
+		}
+	}
+
+	batch.Put(project)
+
+	if opts.DryRun {
+		result.Status = importValid
+		return result
+	}
+
+	err = os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err == nil {
+		err = os.WriteFile(filePath, data, 0644)
+	}
+	if err != nil {
+		result.Status = importFailed
+		result.Message = fmt.Sprintf("Error writing file: %v", err)
+		return result
+	}
+
+	result.Status = importCreated
+	return result
+}
+
+// parseImport reads rows in the given format
+func parseImport(r io.Reader, format string, mapping map[string]string) ([]ImportRow, []string, error) {
+	switch format {
+	case importFormatCSV:
+		return ParseImportCSV(r, mapping)
+	case importFormatJSONL:
+		rows, err := ParseImportJSONL(r)
+		return rows, nil, err
+	}
+	return nil, nil, fmt.Errorf("unsupported import format %q (use csv or jsonl)", format)
+}
+
+// importFormat picks the import format from the format parameter or the content type
+func importFormat(format, contentType string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	switch {
+	case strings.Contains(contentType, "csv"):
+		return importFormatCSV
+	case strings.Contains(contentType, "ndjson"), strings.Contains(contentType, "jsonl"):
+		return importFormatJSONL
+	}
+	return ""
+}
+
+// parseColumnMapping reads "Column=field" pairs
+func parseColumnMapping(pairs []string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, pair := range pairs {
+		column, field, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("invalid column mapping %q (use Column=field)", pair)
+		}
+		mapping[strings.TrimSpace(column)] = strings.TrimSpace(field)
+	}
+	return mapping, nil
+}
+
+// importProjectsHandler imports the CSV or JSONL file in the request body.
+// Columns are mapped with repeated map=Column=field parameters.
+func (s *Server) importProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	query := r.URL.Query()
+	format := importFormat(query.Get("format"), r.Header.Get("Content-Type"))
+	if format == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "format parameter is required (csv or jsonl)")
+		return
+	}
+
+	mapping, err := parseColumnMapping(query["map"])
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows, ignored, err := parseImport(http.MaxBytesReader(w, r.Body, maxImportBytes), format, mapping)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error reading import: %v", err))
+		return
+	}
+
+	report := s.ImportProjects(rows, ImportOptions{
+		DryRun: query.Get("dryRun") == "true",
+		Force:  query.Get("force") == "true",
+	})
+	report.IgnoredColumns = ignored
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// stringListFlag collects the values of a repeatable flag
+type stringListFlag []string
+
+func (f *stringListFlag) String() string { return strings.Join(*f, ", ") }
+
+func (f *stringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// runImportCommand imports projects from a CSV or JSONL file from the command line
+func runImportCommand(args []string) {
+	fs := flag.NewFlagSet("import-projects", flag.ContinueOnError)
+	format := fs.String("format", "", "import format: csv or jsonl (default from the file extension)")
+	dryRun := fs.Bool("dry-run", false, "validate and report without writing files")
+	force := fs.Bool("force", false, "import rows that look like existing projects")
+	jsonReport := fs.Bool("json", false, "print the report as JSON")
+	var mappings stringListFlag
+	fs.Var(&mappings, "map", "map a CSV column to a project field, e.g. -map \"Project=display_name\" (repeatable)")
+
+	cfg, err := LoadConfigFlags(fs, args)
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if fs.NArg() != 1 {
+		log.Fatalf("Usage: import-projects [flags] <file.csv|file.jsonl>")
+	}
+	path := fs.Arg(0)
+
+	mapping, err := parseColumnMapping(mappings)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if *format == "ndjson" {
+			*format = importFormatJSONL
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Error opening import file: %v", err)
+	}
+	defer file.Close()
+
+	rows, ignored, err := parseImport(file, *format, mapping)
+	if err != nil {
+		log.Fatalf("Error reading import: %v", err)
+	}
+
+	s, err := NewServer(cfg)
+	if err != nil {
+		log.Fatalf("Error initializing server: %v", err)
+	}
+	s.loadValidator()
+	s.rebuildIndex()
+
+	report := s.ImportProjects(rows, ImportOptions{DryRun: *dryRun, Force: *force})
+	report.IgnoredColumns = ignored
+
+	if *jsonReport {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding report: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(ignored) > 0 {
+		fmt.Printf("Ignored columns: %s\n", strings.Join(ignored, ", "))
+	}
+	for _, row := range report.Rows {
+		fmt.Printf("line %d\t%s\t%s", row.Line, row.Status, row.Name)
+		if row.Message != "" {
+			fmt.Printf("\t%s", row.Message)
+		}
+		fmt.Println()
+		for _, problem := range row.ValidationErrors {
+			fmt.Printf("  %s: %s\n", problem.Field, problem.Message)
+		}
+		for _, duplicate := range row.Duplicates {
+			fmt.Printf("  possible duplicate of %s (%s)\n", duplicate.Name, strings.Join(duplicate.Reasons, ", "))
+		}
+	}
+
+	statuses := make([]string, 0, len(report.Counts))
+	for status := range report.Counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	summary := make([]string, len(statuses))
+	for i, status := range statuses {
+		summary[i] = fmt.Sprintf("%d %s", report.Counts[status], status)
+	}
+	fmt.Printf("%d row(s): %s\n", len(report.Rows), strings.Join(summary, ", "))
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		mapping map[string]string
+		rows    []ImportRow
+		ignored []string
+		err     string
+	}{
+		{
+			name: "header aliases",
+			csv:  "Name,Display Name,Website,Twitter,GitHub,Notes\nbebop,Bebop,https://bebop.xyz,https://x.com/bebop_dex,https://github.com/bebop-dex,internal\n",
+			rows: []ImportRow{{Line: 2, Project: &Project{
+				Name:        "bebop",
+				DisplayName: "Bebop",
+				Websites:    []URL{{Url: "https://bebop.xyz"}},
+				Github:      []URL{{Url: "https://github.com/bebop-dex"}},
+				Social:      &Social{Twitter: []URL{{Url: "https://x.com/bebop_dex"}}},
+			}}},
+			ignored: []string{"Notes"},
+		},
+		{
+			name:    "mapping",
+			csv:     "Project,Homepage,Owner\nBebop,https://bebop.xyz,someone\n",
+			mapping: map[string]string{"Project": "display_name", "Homepage": "-"},
+			rows:    []ImportRow{{Line: 2, Project: &Project{DisplayName: "Bebop"}}},
+			ignored: []string{"Homepage", "Owner"},
+		},
+		{
+			name: "multi-value cells",
+			csv:  "display_name,websites,comments\nBebop,\"https://bebop.xyz; https://bebop.exchange | https://docs.bebop.xyz\",\"first\nsecond\"\n",
+			rows: []ImportRow{{Line: 2, Project: &Project{
+				DisplayName: "Bebop",
+				Websites:    []URL{{Url: "https://bebop.xyz"}, {Url: "https://bebop.exchange"}, {Url: "https://docs.bebop.xyz"}},
+				Comments:    []string{"first", "second"},
+			}}},
+		},
+		{
+			name: "empty rows and short rows",
+			csv:  "name,display_name,website\nalpha,Alpha\n,,\n\nbeta,Beta,https://beta.xyz\n",
+			rows: []ImportRow{
+				{Line: 2, Project: &Project{Name: "alpha", DisplayName: "Alpha"}},
+				{Line: 5, Project: &Project{Name: "beta", DisplayName: "Beta", Websites: []URL{{Url: "https://beta.xyz"}}}},
+			},
+		},
+		{
+			name:    "unknown mapped field",
+			csv:     "Project\nBebop\n",
+			mapping: map[string]string{"Project": "title_text"},
+			err:     `column "Project" is mapped to unknown field "title_text"`,
+		},
+		{
+			name:    "mapped column not in header",
+			csv:     "Name\nbebop\n",
+			mapping: map[string]string{"Projekt": "display_name"},
+			err:     `mapped column "Projekt" is not in the CSV header`,
+		},
+		{
+			name: "empty file",
+			csv:  "",
+			err:  "CSV file is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, ignored, err := ParseImportCSV(strings.NewReader(tt.csv), tt.mapping)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.rows) {
+				t.Errorf("rows =\n%+v\nwant\n%+v", rows, tt.rows)
+			}
+			if !reflect.DeepEqual(ignored, tt.ignored) {
+				t.Errorf("ignored = %q, want %q", ignored, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestParseImportCSVRowErrors(t *testing.T) {
+	csv := "name,display_name\nalpha,Alpha\nbeta,Be\"ta\ngamma,Gamma\n"
+
+	rows, _, err := ParseImportCSV(strings.NewReader(csv), nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3: %+v", len(rows), rows)
+	}
+	if rows[1].Line != 3 || rows[1].Err == nil || rows[1].Project != nil {
+		t.Errorf("row 2 = %+v, want a parse error on line 3", rows[1])
+	}
+	if rows[2].Line != 4 || rows[2].Project == nil || rows[2].Project.Name != "gamma" {
+		t.Errorf("row 3 = %+v, want gamma on line 4", rows[2])
+	}
+}
+
+func TestParseImportJSONL(t *testing.T) {
+	jsonl := `{"name":"alpha","display_name":"Alpha","websites":[{"url":"https://alpha.xyz"}]}
+
+{"name": "beta",
+{"name":"gamma","display_name":"Gamma"}
+`
+
+	rows, err := ParseImportJSONL(strings.NewReader(jsonl))
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	want := []struct {
+		line int
+		name string
+		err  bool
+	}{
+		{1, "alpha", false},
+		{3, "", true},
+		{4, "gamma", false},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i, w := range want {
+		row := rows[i]
+		if row.Line != w.line || (row.Err != nil) != w.err {
+			t.Errorf("rows[%d] = %+v, want line %d, error %v", i, row, w.line, w.err)
+			continue
+		}
+		if !w.err && row.Project.Name != w.name {
+			t.Errorf("rows[%d].Project.Name = %q, want %q", i, row.Project.Name, w.name)
+		}
+	}
+	if len(rows[0].Project.Websites) != 1 || rows[0].Project.Websites[0].Url != "https://alpha.xyz" {
+		t.Errorf("rows[0].Project = %+v", rows[0].Project)
+	}
+}
+
+func TestImportProjects(t *testing.T) {
+	csv := strings.Join([]string{
+		"Name,Display Name,Website,GitHub",
+		"alpha,Alpha,https://alpha.xyz,https://github.com/alpha-labs",
+		",Beta Protocol,https://beta.xyz,",
+		"existing,Existing,https://existing.xyz,",
+		"alpha,Alpha Again,https://alpha-again.xyz,",
+		"gamma,Gamma,https://gamma.xyz,https://github.com/alpha-labs/gamma",
+		"delta,Delta,https://www.existing.xyz/about,",
+		"Bad Name,,not a url,",
+		`epsilon,Eps"ilon,,`,
+	}, "\n")
+
+	want := []struct {
+		name, status string
+	}{
+		{"alpha", importCreated},
+		{"beta-protocol", importCreated},
+		{"existing", importExists},
+		{"alpha", importDuplicate},
+		{"gamma", importDuplicate},
+		{"delta", importDuplicate},
+		{"Bad Name", importInvalid},
+		{"", importInvalid},
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		name := "write"
+		if dryRun {
+			name = "dry run"
+		}
+		t.Run(name, func(t *testing.T) {
+			s := newTestServer(t, false)
+			checkout := s.config.DirectoryPath
+			writeTestFile(t, checkout, "data/projects/e/existing.yaml", "version: 7\nname: existing\ndisplay_name: Existing\nwebsites:\n- url: https://existing.xyz\n")
+			gitTest(t, checkout, "add", "-A")
+			gitTest(t, checkout, "commit", "-q", "-m", "initial")
+			s.rebuildIndex()
+
+			rows, _, err := ParseImportCSV(strings.NewReader(csv), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report := s.ImportProjects(rows, ImportOptions{DryRun: dryRun})
+
+			created := importCreated
+			if dryRun {
+				created = importValid
+			}
+			if len(report.Rows) != len(want) {
+				t.Fatalf("got %d rows, want %d: %+v", len(report.Rows), len(want), report.Rows)
+			}
+			for i, w := range want {
+				status := w.status
+				if status == importCreated {
+					status = created
+				}
+				if row := report.Rows[i]; row.Name != w.name || row.Status != status {
+					t.Errorf("rows[%d] = %s %s (%s), want %s %s", i, row.Name, row.Status, row.Message, w.name, status)
+				}
+			}
+
+			counts := map[string]int{created: 2, importExists: 1, importDuplicate: 3, importInvalid: 2}
+			if !reflect.DeepEqual(report.Counts, counts) {
+				t.Errorf("counts = %v, want %v", report.Counts, counts)
+			}
+
+			for _, path := range []string{"data/projects/a/alpha.yaml", "data/projects/b/beta-protocol.yaml"} {
+				_, err := os.Stat(filepath.Join(checkout, path))
+				if dryRun && err == nil {
+					t.Errorf("dry run wrote %s", path)
+				}
+				if !dryRun && err != nil {
+					t.Errorf("%s was not written: %v", path, err)
+				}
+			}
+
+			staged := gitTest(t, checkout, "diff", "--cached", "--name-only")
+			wantStaged := "data/projects/a/alpha.yaml\ndata/projects/b/beta-protocol.yaml"
+			if dryRun {
+				wantStaged = ""
+			}
+			if staged != wantStaged {
+				t.Errorf("staged = %q, want %q", staged, wantStaged)
+			}
+		})
+	}
+}
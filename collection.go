@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// CollectionSchemaVersion is the oss-directory collection schema version this tool writes
+const CollectionSchemaVersion = 7
+
+// Collection mirrors the oss-directory collection schema: a named group of projects
+type Collection struct {
+	Version     int      `json:"version" yaml:"version"`
+	Name        string   `json:"name" yaml:"name"`
+	DisplayName string   `json:"displayName" yaml:"display_name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Projects    []string `json:"projects" yaml:"projects"`
+}
+
+// projectExists tells whether the checkout has a file for the named project
+func (s *Server) projectExists(name string) bool {
+	path, err := s.projectPath(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// sortedProjectNames returns the names sorted and without duplicates
+func sortedProjectNames(names []string) []string {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	return slices.Compact(sorted)
+}
+
+// validateCollection checks a collection's fields and that every project it lists exists
+func (s *Server) validateCollection(collection *Collection) []ValidationError {
+	var problems []ValidationError
+
+	if err := ValidateProjectName(collection.Name); err != nil {
+		problems = append(problems, ValidationError{Field: "name", Message: err.Error()})
+	}
+
+	if strings.TrimSpace(collection.DisplayName) == "" {
+		problems = append(problems, ValidationError{Field: "display_name", Message: "display name is required"})
+	}
+
+	for i, name := range collection.Projects {
+		if !s.projectExists(name) {
+			problems = append(problems, ValidationError{
+				Field:   fmt.Sprintf("projects[%d]", i),
+				Message: fmt.Sprintf("project %q does not exist", name),
+			})
+		}
+	}
+
+	return problems
+}
+
+// readCollection loads a collection file both as an ordered document, so unknown keys
+// survive edits, and as a Collection
+func (s *Server) readCollection(name string) (yaml.MapSlice, *Collection, error) {
+	path, err := s.collectionPath(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("error parsing YAML: %v", err)
+	}
+
+	var collection Collection
+	if err := yaml.Unmarshal(data, &collection); err != nil {
+		return nil, nil, fmt.Errorf("error decoding collection: %v", err)
+	}
+	return doc, &collection, nil
+}
+
+// listCollections loads every collection in the checkout, sorted by name
+func (s *Server) listCollections() ([]Collection, error) {
+	paths, err := filepath.Glob(filepath.Join(s.config.DirectoryPath, "data", "collections", "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	collections := []Collection{}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".yaml")
+		_, collection, err := s.readCollection(name)
+		if err != nil {
+			log.Printf("Warning: Skipping collection %s: %v", path, err)
+			continue
+		}
+		collections = append(collections, *collection)
+	}
+
+	sort.Slice(collections, func(i, j int) bool { return collections[i].Name < collections[j].Name })
+	return collections, nil
+}
+
+// writeCollectionDocument writes a collection document and stages it
+func (s *Server) writeCollectionDocument(name string, doc yaml.MapSlice, action string) error {
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("error marshalling YAML: %v", err)
+	}
+
+	path, err := s.collectionPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating collections directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing file: %v", err)
+	}
+
+	s.recordAction(collectionRelPath(name), action)
+	return s.stageFiles(collectionRelPath(name))
+}
+
+// setDocumentKey replaces the value of key in an ordered document, appending it if missing
+func setDocumentKey(doc yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i := range doc {
+		if doc[i].Key == key {
+			doc[i].Value = value
+			return doc
+		}
+	}
+	return append(doc, yaml.MapItem{Key: key, Value: value})
+}
+
+// CollectionResponse reports a collection after a change
+type CollectionResponse struct {
+	Message     string      `json:"message"`
+	Collection  *Collection `json:"collection"`
+	StagedFiles []string    `json:"stagedFiles,omitempty"`
+}
+
+// listCollectionsHandler returns every collection in the directory
+func (s *Server) listCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	collections, err := s.listCollections()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error listing collections: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collections)
+}
+
+// createCollectionHandler writes a new collection file and stages it
+func (s *Server) createCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	var collection Collection
+	if err := json.NewDecoder(r.Body).Decode(&collection); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %v", err))
+		return
+	}
+
+	collection.Version = CollectionSchemaVersion
+	collection.Projects = sortedProjectNames(collection.Projects)
+
+	if problems := s.validateCollection(&collection); len(problems) > 0 {
+		log.Printf("Collection failed validation with %d error(s)", len(problems))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(Response{
+			Error:            "Collection failed validation",
+			ValidationErrors: problems,
+		})
+		return
+	}
+
+	path, err := s.collectionPath(collection.Name)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := os.Stat(path); err == nil {
+		writeErrorResponse(w, http.StatusConflict, fmt.Sprintf("Collection %s already exists", collection.Name))
+		return
+	}
+
+	data, err := yaml.Marshal(&collection)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error marshalling YAML: %v", err))
+		return
+	}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error parsing YAML: %v", err))
+		return
+	}
+
+	if err := s.writeCollectionDocument(collection.Name, doc, actionCollectionCreated); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error saving collection: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CollectionResponse{
+		Message:     "Collection created and changes staged",
+		Collection:  &collection,
+		StagedFiles: s.session.StagedFiles(),
+	})
+}
+
+// collectionProjectHandler returns a handler that adds or removes the project named
+// in the request from a collection's projects list
+func (s *Server) collectionProjectHandler(add bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			setCorsHeaders(w)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+			return
+		}
+
+		setCorsHeaders(w)
+
+		name := r.URL.Query().Get("collection")
+		project := r.URL.Query().Get("project")
+		if err := ValidateProjectName(name); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid collection parameter: %v", err))
+			return
+		}
+		if err := ValidateProjectName(project); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid project parameter: %v", err))
+			return
+		}
+
+		doc, collection, err := s.readCollection(name)
+		if os.IsNotExist(err) {
+			writeErrorResponse(w, http.StatusNotFound, fmt.Sprintf("Collection %s does not exist", name))
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error reading collection file: %v", err))
+			return
+		}
+
+		projects := collection.Projects
+		if add {
+			// Removing a stale name is allowed, but new references must resolve
+			if !s.projectExists(project) {
+				writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Project %s does not exist", project))
+				return
+			}
+			projects = append(projects, project)
+		} else {
+			if !slices.Contains(projects, project) {
+				writeErrorResponse(w, http.StatusNotFound, fmt.Sprintf("Project %s is not in collection %s", project, name))
+				return
+			}
+			projects = slices.DeleteFunc(projects, func(p string) bool { return p == project })
+		}
+		collection.Projects = sortedProjectNames(projects)
+
+		if err := s.writeCollectionDocument(name, setDocumentKey(doc, "projects", collection.Projects), actionCollectionUpdated); err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error saving collection: %v", err))
+			return
+		}
+
+		message := fmt.Sprintf("Added %s to collection %s", project, name)
+		if !add {
+			message = fmt.Sprintf("Removed %s from collection %s", project, name)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CollectionResponse{
+			Message:     message,
+			Collection:  collection,
+			StagedFiles: s.session.StagedFiles(),
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newCollectionFixture returns a server on a checkout with the projects alpha and
+// bebop and the collection defi, which has a key the Collection type does not model
+func newCollectionFixture(t *testing.T) *Server {
+	t.Helper()
+
+	s := newTestServer(t, false)
+	checkout := s.config.DirectoryPath
+	writeTestFile(t, checkout, "data/projects/a/alpha.yaml", "version: 7\nname: alpha\ndisplay_name: Alpha\n")
+	writeTestFile(t, checkout, "data/projects/b/bebop.yaml", "version: 7\nname: bebop\ndisplay_name: Bebop\n")
+	writeTestFile(t, checkout, "data/collections/defi.yaml", "name: defi\nx_curator: someone\nprojects:\n- bebop\nversion: 7\ndisplay_name: DeFi\n")
+	gitTest(t, checkout, "add", "-A")
+	gitTest(t, checkout, "commit", "-q", "-m", "initial")
+	return s
+}
+
+func TestCreateCollectionHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   *Collection
+	}{
+		{
+			"sorted and deduplicated",
+			`{"name": "dexes", "displayName": "DEXes", "projects": ["bebop", "alpha", "bebop"]}`,
+			http.StatusOK,
+			&Collection{Version: CollectionSchemaVersion, Name: "dexes", DisplayName: "DEXes", Projects: []string{"alpha", "bebop"}},
+		},
+		{
+			"version is forced",
+			`{"version": 3, "name": "dexes", "displayName": "DEXes", "projects": []}`,
+			http.StatusOK,
+			&Collection{Version: CollectionSchemaVersion, Name: "dexes", DisplayName: "DEXes", Projects: []string{}},
+		},
+		{"exists", `{"name": "defi", "displayName": "DeFi 2", "projects": ["alpha"]}`, http.StatusConflict, nil},
+		{"missing project", `{"name": "dexes", "displayName": "DEXes", "projects": ["gamma"]}`, http.StatusUnprocessableEntity, nil},
+		{"invalid name", `{"name": "../dexes", "displayName": "DEXes"}`, http.StatusUnprocessableEntity, nil},
+		{"missing display name", `{"name": "dexes"}`, http.StatusUnprocessableEntity, nil},
+		{"bad json", `{"name": `, http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newCollectionFixture(t)
+			checkout := s.config.DirectoryPath
+
+			recorder := httptest.NewRecorder()
+			s.createCollectionHandler(recorder, httptest.NewRequest(http.MethodPost, "/createCollection", bytes.NewBufferString(tt.body)))
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d (%s), want %d", recorder.Code, recorder.Body.String(), tt.status)
+			}
+
+			_, err := os.Stat(filepath.Join(checkout, collectionRelPath("dexes")))
+			if tt.want == nil {
+				if err == nil {
+					t.Error("collection file was written")
+				}
+				return
+			}
+
+			_, collection, err := s.readCollection("dexes")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(collection, tt.want) {
+				t.Errorf("collection = %+v, want %+v", collection, tt.want)
+			}
+			if staged := gitTest(t, checkout, "diff", "--cached", "--name-only"); staged != "data/collections/dexes.yaml" {
+				t.Errorf("staged = %q", staged)
+			}
+		})
+	}
+}
+
+func TestCollectionProjectHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		add    bool
+		query  string
+		status int
+		want   string // Collection file after the request
+	}{
+		{
+			"add keeps the document order",
+			true, "collection=defi&project=alpha",
+			http.StatusOK,
+			"name: defi\nx_curator: someone\nprojects:\n- alpha\n- bebop\nversion: 7\ndisplay_name: DeFi\n",
+		},
+		{
+			"add a listed project",
+			true, "collection=defi&project=bebop",
+			http.StatusOK,
+			"name: defi\nx_curator: someone\nprojects:\n- bebop\nversion: 7\ndisplay_name: DeFi\n",
+		},
+		{
+			"remove",
+			false, "collection=defi&project=bebop",
+			http.StatusOK,
+			"name: defi\nx_curator: someone\nprojects: []\nversion: 7\ndisplay_name: DeFi\n",
+		},
+		{"add a missing project", true, "collection=defi&project=gamma", http.StatusBadRequest, ""},
+		{"remove an unlisted project", false, "collection=defi&project=alpha", http.StatusNotFound, ""},
+		{"missing collection", true, "collection=nfts&project=alpha", http.StatusNotFound, ""},
+		{"invalid collection", true, "collection=../defi&project=alpha", http.StatusBadRequest, ""},
+		{"invalid project", false, "collection=defi&project=Bebop", http.StatusBadRequest, ""},
+	}
+
+	const original = "name: defi\nx_curator: someone\nprojects:\n- bebop\nversion: 7\ndisplay_name: DeFi\n"
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newCollectionFixture(t)
+			checkout := s.config.DirectoryPath
+
+			recorder := httptest.NewRecorder()
+			s.collectionProjectHandler(tt.add)(recorder, httptest.NewRequest(http.MethodPost, "/?"+tt.query, nil))
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d (%s), want %d", recorder.Code, recorder.Body.String(), tt.status)
+			}
+
+			want := tt.want
+			if want == "" {
+				want = original
+			}
+			data, err := os.ReadFile(filepath.Join(checkout, collectionRelPath("defi")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != want {
+				t.Errorf("collection file =\n%s\nwant\n%s", data, want)
+			}
+
+			if tt.status == http.StatusOK {
+				var response CollectionResponse
+				if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+					t.Fatal(err)
+				}
+				if response.Collection == nil || response.Collection.Name != "defi" {
+					t.Errorf("response = %+v", response)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// maxCommitSubjectLength keeps generated subjects readable in one-line logs
+const maxCommitSubjectLength = 72
+
+// Kinds of staged change grouped into a session commit
+const (
+	changeAdded   = "added"
+	changeUpdated = "updated"
+	changeRemoved = "removed"
+	changeRenamed = "renamed"
+)
+
+// CommitChange is one staged file of a session commit
+type CommitChange struct {
+	Path       string `json:"path"`
+	From       string `json:"from,omitempty"` // Source of a rename
+	Change     string `json:"change"`
+	Project    string `json:"project,omitempty"`
+	Logo       bool   `json:"logo,omitempty"`
+	Collection string `json:"collection,omitempty"`
+}
+
+// CommitRequest controls how the session commit is made
+type CommitRequest struct {
+	Body    bool   `json:"body"`              // List every file in the commit body
+	Signoff *bool  `json:"signoff,omitempty"` // Overrides commit_signoff from the config
+	Subject string `json:"subject,omitempty"` // Replaces the generated subject
+}
+
+// CommitResponse describes the commit that was (or, for a preview, would be) made
+type CommitResponse struct {
+	Message string         `json:"message"`
+	Subject string         `json:"subject"`
+	Body    string         `json:"body,omitempty"`
+	Files   []CommitChange `json:"files"`
+	Commit  string         `json:"commit,omitempty"`
+	Git     *GitResult     `json:"git,omitempty"`
+}
+
+// classifyChange maps a git name-status letter and path to a commit change
+func classifyChange(status, path string) CommitChange {
+	change := CommitChange{Path: path, Change: changeUpdated}
+	switch status[0] {
+	case 'A', 'C':
+		change.Change = changeAdded
+	case 'D':
+		change.Change = changeRemoved
+	case 'R':
+		change.Change = changeRenamed
+	}
+
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) == 4 && parts[0] == "data" && parts[1] == "projects" && strings.HasSuffix(parts[3], ".yaml"):
+		change.Project = strings.TrimSuffix(parts[3], ".yaml")
+	case len(parts) >= 3 && parts[0] == "data" && parts[1] == "logos":
+		change.Project = parts[2]
+		change.Logo = true
+	case len(parts) == 3 && parts[0] == "data" && parts[1] == "collections" && strings.HasSuffix(parts[2], ".yaml"):
+		change.Collection = strings.TrimSuffix(parts[2], ".yaml")
+	}
+	return change
+}
+
+// stagedSessionChanges lists the staged files that this tool wrote, and separately the
+// staged paths it did not write
+func (s *Server) stagedSessionChanges() ([]CommitChange, []string, error) {
+	result, err := s.runGit("diff", "--cached", "--name-status", "-z")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing staged files: %v", err)
+	}
+
+	touched := s.session.TouchedPaths()
+
+	changes := []CommitChange{}
+	var foreign []string
+	for _, change := range parseNameStatus(result.Stdout) {
+		for _, path := range []string{change.From, change.Path} {
+			if path != "" && !isTouchedPath(path, touched) {
+				foreign = append(foreign, path)
+			}
+		}
+		if isTouchedPath(change.Path, touched) {
+			changes = append(changes, change)
+		}
+	}
+	return changes, foreign, nil
+}
+
+// parseNameStatus classifies the output of git diff --name-status -z
+func parseNameStatus(output string) []CommitChange {
+	fields := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+
+	var changes []CommitChange
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, path := fields[i], fields[i+1]
+		if status == "" {
+			continue
+		}
+		// Renames and copies list the source before the destination
+		var from string
+		if status[0] == 'R' || status[0] == 'C' {
+			i++
+			if i+1 >= len(fields) {
+				break
+			}
+			from, path = path, fields[i+1]
+		}
+		change := classifyChange(status, path)
+		if status[0] == 'R' {
+			change.From = from
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// BuildCommitMessage generates a conventional commit subject summarizing the
+// project changes, e.g. "feat: add 5 projects (bebop-dex, ...)", and a body
+// listing every file
+func BuildCommitMessage(changes []CommitChange) (string, string) {
+	groups := map[string][]string{}
+	collections := map[string][]string{}
+	var logos []string
+	seen := map[string]bool{}
+
+	for _, change := range changes {
+		if change.Collection != "" {
+			collections[change.Change] = append(collections[change.Change], change.Collection)
+			continue
+		}
+		if change.Project == "" || change.Logo {
+			continue
+		}
+		groups[change.Change] = append(groups[change.Change], change.Project)
+		seen[change.Project] = true
+	}
+	for _, change := range changes {
+		if change.Logo && !seen[change.Project] {
+			logos = append(logos, change.Project)
+			seen[change.Project] = true
+		}
+	}
+
+	var parts, names []string
+	for _, group := range []struct{ change, verb string }{
+		{changeAdded, "add"},
+		{changeUpdated, "update"},
+		{changeRenamed, "rename"},
+		{changeRemoved, "remove"},
+	} {
+		if projects := groups[group.change]; len(projects) > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", group.verb, plural(len(projects), "project")))
+			names = append(names, projects...)
+		}
+	}
+	if len(logos) > 0 {
+		parts = append(parts, "update logos of "+plural(len(logos), "project"))
+		names = append(names, logos...)
+	}
+	for _, group := range []struct{ change, verb string }{
+		{changeAdded, "add"},
+		{changeUpdated, "update"},
+		{changeRemoved, "remove"},
+	} {
+		if changed := collections[group.change]; len(changed) > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", group.verb, plural(len(changed), "collection")))
+			names = append(names, changed...)
+		}
+	}
+
+	commitType := "chore"
+	switch {
+	case len(groups[changeAdded]) > 0 || len(collections[changeAdded]) > 0:
+		commitType = "feat"
+	case len(groups[changeUpdated]) > 0 || len(groups[changeRenamed]) > 0:
+		commitType = "fix"
+	}
+
+	var subject string
+	switch len(parts) {
+	case 0:
+		subject = "chore: update " + plural(len(changes), "file")
+	case 1:
+		subject = commitType + ": " + parts[0]
+	default:
+		subject = commitType + ": " + strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+	}
+	if len(subject) > maxCommitSubjectLength {
+		// Too many kinds of changes to list, so only count what changed
+		changed := []string{plural(len(names)-countCollections(collections), "project")}
+		if n := countCollections(collections); n > 0 {
+			changed = append(changed, plural(n, "collection"))
+		}
+		subject = commitType + ": update " + strings.Join(changed, " and ")
+	}
+	if len(names) > 0 {
+		if listed := truncateNames(names, maxCommitSubjectLength-len(subject)-3); listed != "" {
+			subject += " (" + listed + ")"
+		}
+	}
+
+	var body strings.Builder
+	for _, change := range changes {
+		if change.From != "" {
+			fmt.Fprintf(&body, "- %s %s -> %s\n", change.Change, change.From, change.Path)
+			continue
+		}
+		fmt.Fprintf(&body, "- %s %s\n", change.Change, change.Path)
+	}
+
+	return subject, strings.TrimSuffix(body.String(), "\n")
+}
+
+// plural formats a count of things, e.g. "1 project" or "5 projects"
+func plural(n int, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
+
+// countCollections returns the number of collection changes
+func countCollections(collections map[string][]string) int {
+	n := 0
+	for _, changed := range collections {
+		n += len(changed)
+	}
+	return n
+}
+
+// truncateNames joins as many names as fit in width, ending in "..." when some are
+// left out, or returns "" when not even the first name fits
+func truncateNames(names []string, width int) string {
+	first := len(names[0])
+	if len(names) > 1 {
+		first += len(", ...")
+	}
+	if first > width {
+		return ""
+	}
+
+	joined := names[0]
+	for i, name := range names[1:] {
+		rest := ""
+		if i+2 < len(names) {
+			rest = ", ..."
+		}
+		if len(joined)+2+len(name)+len(rest) > width {
+			return joined + ", ..."
+		}
+		joined += ", " + name
+	}
+	return joined
+}
+
+// commitArgs builds the git arguments for a session commit of the index, applying the
+// configured author identity and sign-off
+func (s *Server) commitArgs(subject, body string, signoff bool) []string {
+	var args []string
+	if s.config.CommitAuthorName != "" {
+		args = append(args, "-c", "user.name="+s.config.CommitAuthorName, "-c", "user.email="+s.config.CommitAuthorEmail)
+	}
+
+	args = append(args, "commit", "-m", subject)
+	if body != "" {
+		args = append(args, "-m", body)
+	}
+	if signoff {
+		args = append(args, "--signoff")
+	}
+	return args
+}
+
+// commitSessionHandler commits the staged files written by this tool with a generated
+// message. GET previews the message without committing.
+func (s *Server) commitSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	var req CommitRequest
+	if r.Method == http.MethodPost && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %v", err))
+			return
+		}
+	}
+	if r.Method == http.MethodGet {
+		req.Body = r.URL.Query().Get("body") == "true"
+	}
+
+	changes, foreign, err := s.stagedSessionChanges()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// The index is committed as staged, so it must hold nothing but the tool's changes
+	if len(foreign) > 0 {
+		writeErrorResponse(w, http.StatusConflict, fmt.Sprintf("Files not written by this tool are staged; unstage them first: %s", strings.Join(foreign, ", ")))
+		return
+	}
+	if len(changes) == 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "No staged changes from this session to commit")
+		return
+	}
+
+	subject, body := BuildCommitMessage(changes)
+	if custom := strings.TrimSpace(req.Subject); custom != "" {
+		subject = custom
+	}
+	if !req.Body {
+		body = ""
+	}
+
+	response := CommitResponse{
+		Subject: subject,
+		Body:    body,
+		Files:   changes,
+	}
+
+	if r.Method == http.MethodGet {
+		response.Message = "Commit preview"
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	signoff := s.config.CommitSignoff
+	if req.Signoff != nil {
+		signoff = *req.Signoff
+	}
+
+	// Commit the index rather than the paths, so unstaged edits to the same files stay out
+	result, err := s.runGit(s.commitArgs(subject, body, signoff)...)
+	response.Git = result
+	if err != nil {
+		log.Printf("Session commit failed: %v\nStderr: %s", err, result.Stderr)
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error committing changes: %v\nOutput: %s", err, result.Stderr))
+		return
+	}
+
+	if head, err := s.runGit("rev-parse", "HEAD"); err == nil {
+		response.Commit = strings.TrimSpace(head.Stdout)
+	}
+
+	// The batch is done; start a new one
+	if files, err := s.gitStatus(); err == nil {
+		s.pruneTouchedPaths(files)
+	}
+	if err := s.refreshStagedFiles(); err != nil {
+		log.Printf("Warning: Failed to refresh staged files: %v", err)
+	}
+	if err := s.session.ResetFiles(); err != nil {
+		log.Printf("Warning: Failed to reset session files: %v", err)
+	}
+
+	response.Message = "Changes committed"
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
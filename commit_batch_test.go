@@ -0,0 +1,156 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNameStatus(t *testing.T) {
+	output := strings.Join([]string{
+		"A", "data/projects/b/bebop.yaml",
+		"M", "data/collections/defi.yaml",
+		"D", "data/logos/old/favicon.png",
+		"R097", "data/projects/b/bebop-dex.yaml", "data/projects/b/bebop.yaml",
+		"C100", "data/logos/a/logo.json", "data/logos/b/logo.json",
+		"M", "README.md",
+	}, "\x00") + "\x00"
+
+	want := []CommitChange{
+		{Path: "data/projects/b/bebop.yaml", Change: changeAdded, Project: "bebop"},
+		{Path: "data/collections/defi.yaml", Change: changeUpdated, Collection: "defi"},
+		{Path: "data/logos/old/favicon.png", Change: changeRemoved, Project: "old", Logo: true},
+		{Path: "data/projects/b/bebop.yaml", From: "data/projects/b/bebop-dex.yaml", Change: changeRenamed, Project: "bebop"},
+		{Path: "data/logos/b/logo.json", Change: changeAdded, Project: "b", Logo: true},
+		{Path: "README.md", Change: changeUpdated},
+	}
+
+	if got := parseNameStatus(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNameStatus() =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := parseNameStatus(""); len(got) != 0 {
+		t.Errorf("parseNameStatus(\"\") = %+v, want nothing", got)
+	}
+}
+
+func TestBuildCommitMessage(t *testing.T) {
+	project := func(change, name string) CommitChange {
+		return CommitChange{Path: projectRelPath(name), Change: change, Project: name}
+	}
+	logo := func(change, name string) CommitChange {
+		return CommitChange{Path: "data/logos/" + name + "/favicon.png", Change: change, Project: name, Logo: true}
+	}
+	collection := func(change, name string) CommitChange {
+		return CommitChange{Path: collectionRelPath(name), Change: change, Collection: name}
+	}
+
+	tests := []struct {
+		name    string
+		changes []CommitChange
+		subject string
+	}{
+		{
+			"one added project with its logo",
+			[]CommitChange{project(changeAdded, "bebop"), logo(changeAdded, "bebop")},
+			"feat: add 1 project (bebop)",
+		},
+		{
+			"updates only",
+			[]CommitChange{project(changeUpdated, "alpha"), project(changeUpdated, "beta")},
+			"fix: update 2 projects (alpha, beta)",
+		},
+		{
+			"logo only",
+			[]CommitChange{logo(changeUpdated, "alpha")},
+			"chore: update logos of 1 project (alpha)",
+		},
+		{
+			"mixed add, update and rename",
+			[]CommitChange{
+				project(changeAdded, "alpha"),
+				project(changeUpdated, "beta"),
+				{Path: projectRelPath("gamma"), From: projectRelPath("gama"), Change: changeRenamed, Project: "gamma"},
+				logo(changeRenamed, "gamma"),
+				collection(changeUpdated, "defi"),
+			},
+			"feat: update 3 projects and 1 collection (alpha, beta, gamma, defi)",
+		},
+		{
+			"rename",
+			[]CommitChange{
+				{Path: projectRelPath("bebop"), From: projectRelPath("bebop-dex"), Change: changeRenamed, Project: "bebop"},
+				collection(changeUpdated, "defi"),
+			},
+			"fix: rename 1 project and update 1 collection (bebop, defi)",
+		},
+		{
+			"removal only",
+			[]CommitChange{project(changeRemoved, "alpha")},
+			"chore: remove 1 project (alpha)",
+		},
+		{
+			"name too long for the subject",
+			[]CommitChange{project(changeAdded, strings.Repeat("very-long-name-", 4)+"protocol")},
+			"feat: add 1 project",
+		},
+		{
+			"many kinds of changes",
+			[]CommitChange{
+				project(changeAdded, "alpha-protocol"),
+				project(changeUpdated, "beta-protocol"),
+				project(changeRemoved, "gamma-protocol"),
+				logo(changeUpdated, "delta"),
+				collection(changeAdded, "defi"),
+				collection(changeRemoved, "old"),
+			},
+			"feat: update 4 projects and 2 collections (alpha-protocol, ...)",
+		},
+		{
+			"no project files",
+			[]CommitChange{{Path: "README.md", Change: changeUpdated}},
+			"chore: update 1 file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, _ := BuildCommitMessage(tt.changes)
+			if subject != tt.subject {
+				t.Errorf("subject = %q, want %q", subject, tt.subject)
+			}
+			if len(subject) > maxCommitSubjectLength {
+				t.Errorf("subject %q is %d characters, over %d", subject, len(subject), maxCommitSubjectLength)
+			}
+		})
+	}
+}
+
+func TestBuildCommitMessageTruncatesNames(t *testing.T) {
+	var changes []CommitChange
+	for _, name := range []string{"alpha-protocol", "beta-protocol", "gamma-protocol", "delta-protocol", "epsilon-protocol", "zeta-protocol"} {
+		changes = append(changes, CommitChange{Path: projectRelPath(name), Change: changeAdded, Project: name})
+	}
+
+	subject, body := BuildCommitMessage(changes)
+	if len(subject) > maxCommitSubjectLength {
+		t.Errorf("subject %q is %d characters, over %d", subject, len(subject), maxCommitSubjectLength)
+	}
+	if !strings.HasPrefix(subject, "feat: add 6 projects (alpha-protocol, ") || !strings.HasSuffix(subject, ", ...)") {
+		t.Errorf("subject = %q, want the first names followed by \", ...\"", subject)
+	}
+	if strings.Count(body, "\n")+1 != len(changes) {
+		t.Errorf("body = %q, want one line per file", body)
+	}
+}
+
+func TestBuildCommitMessageBodyListsRenames(t *testing.T) {
+	_, body := BuildCommitMessage([]CommitChange{
+		{Path: projectRelPath("bebop"), From: projectRelPath("bebop-dex"), Change: changeRenamed, Project: "bebop"},
+		{Path: collectionRelPath("defi"), Change: changeUpdated, Collection: "defi"},
+	})
+
+	want := "- renamed data/projects/b/bebop-dex.yaml -> data/projects/b/bebop.yaml\n- updated data/collections/defi.yaml"
+	if body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
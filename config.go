@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config holds the settings the server needs to operate on an oss-directory checkout
+type Config struct {
+	DirectoryPath  string `yaml:"directory_path"`  // Root of the local oss-directory checkout
+	ListenAddr     string `yaml:"listen_addr"`     // Address the HTTP server listens on
+	UpstreamRemote string `yaml:"upstream_remote"` // Git remote that tracks the upstream oss-directory
+	UpstreamBranch string `yaml:"upstream_branch"` // Branch of the upstream remote to merge from
+	OriginRemote   string `yaml:"origin_remote"`   // Git remote (usually a fork) that pull and push operate on
+	SchemaDir      string `yaml:"schema_dir"`      // JSON schema directory, relative to the checkout unless absolute
+	StateDir       string `yaml:"state_dir"`       // Directory where session state is persisted
+	DevMode        bool   `yaml:"dev_mode"`        // Allow requests to relax the outbound fetch policy
+	GitHubAPIURL   string `yaml:"github_api_url"`  // Base URL of the GitHub REST API
+	GitHubToken    string `yaml:"github_token"`    // Token for the GitHub REST API, optional for reads
+	GitHubFixtures string `yaml:"github_fixtures"` // Directory of offline GitHub fixtures used instead of the API
+
+	CommitAuthorName  string `yaml:"commit_author_name"`  // Author of session commits; git's own identity when empty
+	CommitAuthorEmail string `yaml:"commit_author_email"` // Email of the session commit author
+	CommitSignoff     bool   `yaml:"commit_signoff"`      // Add a Signed-off-by trailer to session commits
+}
+
+// Environment variables that override values from the config file
+const (
+	envConfigFile     = "OSS_ADDER_CONFIG"
+	envDirectoryPath  = "OSS_ADDER_DIRECTORY"
+	envListenAddr     = "OSS_ADDER_LISTEN_ADDR"
+	envUpstreamRemote = "OSS_ADDER_UPSTREAM_REMOTE"
+	envUpstreamBranch = "OSS_ADDER_UPSTREAM_BRANCH"
+	envOriginRemote   = "OSS_ADDER_ORIGIN_REMOTE"
+	envSchemaDir      = "OSS_ADDER_SCHEMA_DIR"
+	envStateDir       = "OSS_ADDER_STATE_DIR"
+	envDevMode        = "OSS_ADDER_DEV_MODE"
+	envGitHubAPIURL   = "OSS_ADDER_GITHUB_API_URL"
+	envGitHubToken    = "OSS_ADDER_GITHUB_TOKEN"
+	envGitHubFixtures = "OSS_ADDER_GITHUB_FIXTURES"
+
+	envCommitAuthorName  = "OSS_ADDER_COMMIT_AUTHOR_NAME"
+	envCommitAuthorEmail = "OSS_ADDER_COMMIT_AUTHOR_EMAIL"
+	envCommitSignoff     = "OSS_ADDER_COMMIT_SIGNOFF"
+)
+
+// DefaultConfig returns the configuration used when nothing else is specified
+func DefaultConfig() Config {
+	return Config{
+		ListenAddr:     ":8080",
+		UpstreamRemote: "upstream",
+		UpstreamBranch: "main",
+		OriginRemote:   "origin",
+		SchemaDir:      filepath.Join("src", "resources", "schema"),
+		StateDir:       defaultStateDir(),
+		GitHubAPIURL:   "https://api.github.com",
+	}
+}
+
+// defaultStateDir places session state in the user's config directory when available
+func defaultStateDir() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "oss_project_adder")
+	}
+	return ".oss_project_adder"
+}
+
+// LoadConfig builds the configuration from defaults, an optional YAML config file,
+// environment variables and command line flags, in increasing order of precedence
+func LoadConfig(args []string) (Config, error) {
+	return LoadConfigFlags(flag.NewFlagSet("yaml_project_creator", flag.ContinueOnError), args)
+}
+
+// LoadConfigFlags is LoadConfig for subcommands that define extra flags on fs
+func LoadConfigFlags(fs *flag.FlagSet, args []string) (Config, error) {
+	cfg := DefaultConfig()
+
+	configFile := fs.String("config", os.Getenv(envConfigFile), "path to an optional YAML config file")
+	directoryPath := fs.String("dir", "", "path to the local oss-directory checkout")
+	listenAddr := fs.String("addr", "", "address the HTTP server listens on")
+	upstreamRemote := fs.String("upstream-remote", "", "git remote that tracks the upstream oss-directory")
+	upstreamBranch := fs.String("upstream-branch", "", "upstream branch to merge from on startup")
+	originRemote := fs.String("origin-remote", "", "git remote that pull and push operate on")
+	schemaDir := fs.String("schema-dir", "", "JSON schema directory, relative to the checkout unless absolute")
+	stateDir := fs.String("state-dir", "", "directory where session state is persisted")
+	githubAPIURL := fs.String("github-api-url", "", "base URL of the GitHub REST API")
+	githubFixtures := fs.String("github-fixtures", "", "directory of offline GitHub fixtures used instead of the API")
+	commitAuthorName := fs.String("commit-author-name", "", "author name of session commits")
+	commitAuthorEmail := fs.String("commit-author-email", "", "author email of session commits")
+	commitSignoff := fs.Bool("commit-signoff", false, "add a Signed-off-by trailer to session commits")
+	devMode := fs.Bool("dev", false, "allow insecure=true on fetch requests (skips TLS verification and address checks)")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return cfg, err
+		}
+	}
+
+	cfg.applyEnv()
+
+	// Only flags that were given explicitly override the other sources
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "dir":
+			cfg.DirectoryPath = *directoryPath
+		case "addr":
+			cfg.ListenAddr = *listenAddr
+		case "upstream-remote":
+			cfg.UpstreamRemote = *upstreamRemote
+		case "upstream-branch":
+			cfg.UpstreamBranch = *upstreamBranch
+		case "origin-remote":
+			cfg.OriginRemote = *originRemote
+		case "schema-dir":
+			cfg.SchemaDir = *schemaDir
+		case "state-dir":
+			cfg.StateDir = *stateDir
+		case "dev":
+			cfg.DevMode = *devMode
+		case "github-api-url":
+			cfg.GitHubAPIURL = *githubAPIURL
+		case "github-fixtures":
+			cfg.GitHubFixtures = *githubFixtures
+		case "commit-author-name":
+			cfg.CommitAuthorName = *commitAuthorName
+		case "commit-author-email":
+			cfg.CommitAuthorEmail = *commitAuthorEmail
+		case "commit-signoff":
+			cfg.CommitSignoff = *commitSignoff
+		}
+	})
+
+	if err := cfg.normalize(); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile merges the values of a YAML config file into the configuration
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening config file: %v", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// applyEnv overrides configuration values with any environment variables that are set
+func (c *Config) applyEnv() {
+	if v := os.Getenv(envDirectoryPath); v != "" {
+		c.DirectoryPath = v
+	}
+	if v := os.Getenv(envListenAddr); v != "" {
+		c.ListenAddr = v
+	}
+	if v := os.Getenv(envUpstreamRemote); v != "" {
+		c.UpstreamRemote = v
+	}
+	if v := os.Getenv(envUpstreamBranch); v != "" {
+		c.UpstreamBranch = v
+	}
+	if v := os.Getenv(envOriginRemote); v != "" {
+		c.OriginRemote = v
+	}
+	if v := os.Getenv(envSchemaDir); v != "" {
+		c.SchemaDir = v
+	}
+	if v := os.Getenv(envStateDir); v != "" {
+		c.StateDir = v
+	}
+	if v := os.Getenv(envDevMode); v != "" {
+		c.DevMode = v == "1" || strings.EqualFold(v, "true")
+	}
+	if v := os.Getenv(envGitHubAPIURL); v != "" {
+		c.GitHubAPIURL = v
+	}
+	if v := os.Getenv(envGitHubToken); v != "" {
+		c.GitHubToken = v
+	}
+	if v := os.Getenv(envGitHubFixtures); v != "" {
+		c.GitHubFixtures = v
+	}
+	if v := os.Getenv(envCommitAuthorName); v != "" {
+		c.CommitAuthorName = v
+	}
+	if v := os.Getenv(envCommitAuthorEmail); v != "" {
+		c.CommitAuthorEmail = v
+	}
+	if v := os.Getenv(envCommitSignoff); v != "" {
+		c.CommitSignoff = v == "1" || strings.EqualFold(v, "true")
+	}
+}
+
+// normalize checks required values and turns the directory path into an absolute path
+func (c *Config) normalize() error {
+	if c.DirectoryPath == "" {
+		return errors.New("oss-directory path is required (use -dir, " + envDirectoryPath + " or directory_path in the config file)")
+	}
+
+	if c.DirectoryPath == "~" || strings.HasPrefix(c.DirectoryPath, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("error resolving home directory: %v", err)
+		}
+		c.DirectoryPath = filepath.Join(home, strings.TrimPrefix(c.DirectoryPath, "~"))
+	}
+
+	absPath, err := filepath.Abs(c.DirectoryPath)
+	if err != nil {
+		return fmt.Errorf("error resolving oss-directory path: %v", err)
+	}
+	c.DirectoryPath = absPath
+
+	info, err := os.Stat(c.DirectoryPath)
+	if err != nil {
+		return fmt.Errorf("error accessing oss-directory path: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("oss-directory path %s is not a directory", c.DirectoryPath)
+	}
+
+	if c.StateDir == "" {
+		return errors.New("state directory cannot be empty")
+	}
+
+	if c.ListenAddr == "" {
+		return errors.New("listen address cannot be empty")
+	}
+	if c.UpstreamRemote == "" || c.UpstreamBranch == "" {
+		return errors.New("upstream remote and branch cannot be empty")
+	}
+	if c.OriginRemote == "" {
+		return errors.New("origin remote cannot be empty")
+	}
+
+	c.GitHubAPIURL = strings.TrimSuffix(c.GitHubAPIURL, "/")
+	if c.GitHubAPIURL == "" {
+		return errors.New("GitHub API URL cannot be empty")
+	}
+
+	if (c.CommitAuthorName == "") != (c.CommitAuthorEmail == "") {
+		return errors.New("commit author name and email must be set together")
+	}
+
+	return nil
+}
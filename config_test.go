@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearConfigEnv unsets every configuration environment variable for the test
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+
+	for _, name := range []string{
+		envConfigFile, envDirectoryPath, envListenAddr, envUpstreamRemote, envUpstreamBranch,
+		envOriginRemote, envSchemaDir, envStateDir, envDevMode, envGitHubAPIURL, envGitHubToken,
+		envGitHubFixtures, envCommitAuthorName, envCommitAuthorEmail, envCommitSignoff,
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	fileDir := t.TempDir()
+	envDir := t.TempDir()
+	flagDir := t.TempDir()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestFile(t, filepath.Dir(configFile), "config.yaml", strings.Join([]string{
+		"directory_path: " + fileDir,
+		"listen_addr: :9000",
+		"upstream_branch: develop",
+		"origin_remote: fork",
+		"dev_mode: true",
+		"commit_signoff: true",
+		"github_api_url: https://github.example.com/api/v3/",
+		"",
+	}, "\n"))
+
+	tests := []struct {
+		name  string
+		env   map[string]string
+		args  []string
+		check func(t *testing.T, cfg Config)
+	}{
+		{
+			"defaults",
+			nil,
+			[]string{"-dir", flagDir},
+			func(t *testing.T, cfg Config) {
+				want := DefaultConfig()
+				want.DirectoryPath = flagDir
+				if cfg != want {
+					t.Errorf("config = %+v, want %+v", cfg, want)
+				}
+			},
+		},
+		{
+			"file overrides defaults",
+			nil,
+			[]string{"-config", configFile},
+			func(t *testing.T, cfg Config) {
+				if cfg.DirectoryPath != fileDir || cfg.ListenAddr != ":9000" || cfg.UpstreamBranch != "develop" || cfg.OriginRemote != "fork" {
+					t.Errorf("config = %+v, want the values of the file", cfg)
+				}
+				if cfg.UpstreamRemote != "upstream" {
+					t.Errorf("upstream remote = %q, want the default", cfg.UpstreamRemote)
+				}
+				if !cfg.DevMode || !cfg.CommitSignoff {
+					t.Errorf("dev mode %v, signoff %v; want both from the file", cfg.DevMode, cfg.CommitSignoff)
+				}
+				if cfg.GitHubAPIURL != "https://github.example.com/api/v3" {
+					t.Errorf("GitHub API URL = %q, want the trailing slash trimmed", cfg.GitHubAPIURL)
+				}
+			},
+		},
+		{
+			"environment overrides the file",
+			map[string]string{envConfigFile: configFile, envDirectoryPath: envDir, envListenAddr: ":9100", envDevMode: "0"},
+			nil,
+			func(t *testing.T, cfg Config) {
+				if cfg.DirectoryPath != envDir || cfg.ListenAddr != ":9100" || cfg.DevMode {
+					t.Errorf("config = %+v, want the values of the environment", cfg)
+				}
+				if cfg.UpstreamBranch != "develop" {
+					t.Errorf("upstream branch = %q, want the file value", cfg.UpstreamBranch)
+				}
+			},
+		},
+		{
+			"flags override the environment",
+			map[string]string{envConfigFile: configFile, envDirectoryPath: envDir, envListenAddr: ":9100", envOriginRemote: "mine"},
+			[]string{"-dir", flagDir, "-addr", ":9200"},
+			func(t *testing.T, cfg Config) {
+				if cfg.DirectoryPath != flagDir || cfg.ListenAddr != ":9200" {
+					t.Errorf("config = %+v, want the values of the flags", cfg)
+				}
+				if cfg.OriginRemote != "mine" {
+					t.Errorf("origin remote = %q, want the environment value", cfg.OriginRemote)
+				}
+			},
+		},
+		{
+			"explicit false flags override true values",
+			map[string]string{envConfigFile: configFile, envCommitSignoff: "true"},
+			[]string{"-dev=false", "-commit-signoff=false"},
+			func(t *testing.T, cfg Config) {
+				if cfg.DevMode || cfg.CommitSignoff {
+					t.Errorf("dev mode %v, signoff %v; want both false", cfg.DevMode, cfg.CommitSignoff)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := LoadConfig(tt.args)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "unknown.yaml", "directory_path: "+dir+"\ncolour: blue\n")
+	writeTestFile(t, dir, "file", "")
+
+	tests := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{"missing directory", nil, "oss-directory path is required"},
+		{"directory is a file", []string{"-dir", filepath.Join(dir, "file")}, "is not a directory"},
+		{"unknown key in the file", []string{"-config", filepath.Join(dir, "unknown.yaml")}, "error parsing config file"},
+		{"missing file", []string{"-config", filepath.Join(dir, "missing.yaml")}, "error opening config file"},
+		{"empty flag value", []string{"-dir", dir, "-origin-remote", ""}, "origin remote cannot be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			if _, err := LoadConfig(tt.args); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("LoadConfig(%q) error = %v, want %q", tt.args, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFlagsExtraFlags(t *testing.T) {
+	clearConfigEnv(t)
+	dir := t.TempDir()
+
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "")
+	cfg, err := LoadConfigFlags(fs, []string{"-dir", dir, "-dry-run", "rows.csv"})
+	if err != nil {
+		t.Fatalf("LoadConfigFlags() error = %v", err)
+	}
+	if cfg.DirectoryPath != dir || !*dryRun || fs.Arg(0) != "rows.csv" {
+		t.Errorf("directory %q, dry run %v, args %q", cfg.DirectoryPath, *dryRun, fs.Args())
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DuplicateCandidate is an existing project that may describe the same thing as a new one
+type DuplicateCandidate struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"`
+	Reasons []string `json:"reasons"`
+}
+
+// Hosts shared by many projects, where the first path segment identifies the project
+var sharedHosts = map[string]bool{
+	"github.com":      true,
+	"twitter.com":     true,
+	"x.com":           true,
+	"t.me":            true,
+	"discord.gg":      true,
+	"discord.com":     true,
+	"mirror.xyz":      true,
+	"medium.com":      true,
+	"warpcast.com":    true,
+	"youtube.com":     true,
+	"linkedin.com":    true,
+	"linktr.ee":       true,
+	"npmjs.com":       true,
+	"crates.io":       true,
+	"pypi.org":        true,
+	"modules.io":      true,
+	"gitlab.com":      true,
+	"notion.so":       true,
+	"docs.google.com": true,
+}
+
+// Path segments that only introduce the handle on shared hosts, e.g. the "package" of
+// npmjs.com/package/<name>. The handle is the first segment after them.
+var handlePrefixes = map[string][]string{
+	"github.com":      {"orgs"},
+	"npmjs.com":       {"package"},
+	"crates.io":       {"crates"},
+	"pypi.org":        {"project"},
+	"modules.io":      {"modules"},
+	"linkedin.com":    {"company", "in", "school", "showcase"},
+	"youtube.com":     {"channel", "c", "user"},
+	"docs.google.com": {"document", "spreadsheets", "presentation", "forms", "d"},
+	"discord.com":     {"channels"},
+}
+
+// Hosts that are aliases of each other
+var hostAliases = map[string]string{
+	"twitter.com":        "x.com",
+	"mobile.twitter.com": "x.com",
+	"telegram.me":        "t.me",
+	"discordapp.com":     "discord.com",
+}
+
+// FindDuplicates returns indexed projects that share an identity with the given project:
+// the same GitHub organization, website domain or social handle, or a near-identical name
+func (idx *ProjectIndex) FindDuplicates(project *Project) []DuplicateCandidate {
+	keys := identityKeys(project)
+	slugs := nameSlugs(project)
+
+	var candidates []DuplicateCandidate
+	for _, entry := range idx.All() {
+		var reasons []string
+
+		if entry.Project.Name == project.Name {
+			reasons = append(reasons, "same project name")
+		}
+
+		for key, description := range identityKeys(entry.Project) {
+			if _, ok := keys[key]; ok {
+				reasons = append(reasons, description)
+			}
+		}
+
+		if reason := similarNameReason(slugs, nameSlugs(entry.Project)); reason != "" && entry.Project.Name != project.Name {
+			reasons = append(reasons, reason)
+		}
+
+		if len(reasons) > 0 {
+			sort.Strings(reasons)
+			candidates = append(candidates, DuplicateCandidate{
+				Name:    entry.Project.Name,
+				Path:    entry.Path,
+				Reasons: reasons,
+			})
+		}
+	}
+
+	// Projects sharing the most evidence come first
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].Reasons) > len(candidates[j].Reasons)
+	})
+	return candidates
+}
+
+// identityKeys maps the identifying keys of a project's URLs to a human-readable reason
+func identityKeys(project *Project) map[string]string {
+	keys := make(map[string]string)
+
+	for _, field := range projectURLFields(project) {
+		for _, u := range field.urls {
+			host, segment := urlIdentity(u.Url)
+			if host == "" {
+				continue
+			}
+
+			switch {
+			case host == "github.com" && segment != "":
+				keys["github:"+segment] = fmt.Sprintf("same GitHub organization %q", segment)
+			case sharedHosts[host] && segment != "":
+				keys["handle:"+host+"/"+segment] = fmt.Sprintf("same handle %s/%s", host, segment)
+			case !sharedHosts[host] && field.name == "websites":
+				keys["domain:"+host] = fmt.Sprintf("same website domain %s", host)
+			}
+		}
+	}
+
+	return keys
+}
+
+// urlIdentity returns the normalized host of a URL and the handle in its path: the
+// first segment, or on hosts with handlePrefixes the prefixes and the segment after them
+func urlIdentity(raw string) (string, string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Hostname() == "" {
+		return "", ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if alias, ok := hostAliases[host]; ok {
+		host = alias
+	}
+
+	// Subdomain handles such as <name>.mirror.xyz are treated like path handles
+	if strings.HasSuffix(host, ".mirror.xyz") {
+		return "mirror.xyz", strings.TrimSuffix(host, ".mirror.xyz")
+	}
+
+	parts := strings.Split(strings.ToLower(strings.Trim(parsed.Path, "/")), "/")
+	segment := strings.TrimPrefix(parts[0], "@")
+	if (host == "discord.com" || host == "discord.gg") && segment == "invite" {
+		if len(parts) > 1 {
+			segment = parts[1]
+		}
+	}
+	if prefixes := handlePrefixes[host]; slices.Contains(prefixes, segment) {
+		// A prefix without the segment naming the project is no handle
+		i := 0
+		for i < len(parts) && slices.Contains(prefixes, parts[i]) {
+			i++
+		}
+		segment = ""
+		if i < len(parts) && parts[i] != "" {
+			segment = strings.Join(parts[:i+1], "/")
+		}
+	}
+	if host == "discord.com" {
+		host = "discord.gg"
+	}
+
+	return host, segment
+}
+
+// nameSlugs returns the normalized slugs of a project's name and display name
+func nameSlugs(project *Project) []string {
+	var slugs []string
+	for _, name := range []string{project.Name, project.DisplayName} {
+		if slug := GenerateSlug(name); slug != "" && !slices.Contains(slugs, slug) {
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
+
+// similarNameReason describes why two sets of name slugs look alike, or returns ""
+func similarNameReason(a, b []string) string {
+	for _, x := range a {
+		for _, y := range b {
+			compactX := strings.ReplaceAll(x, "-", "")
+			compactY := strings.ReplaceAll(y, "-", "")
+
+			if compactX == compactY {
+				return fmt.Sprintf("same normalized name %q", x)
+			}
+
+			// Allow one edit for short names and two for longer ones
+			maxDistance := 1
+			if len(compactX) >= 8 && len(compactY) >= 8 {
+				maxDistance = 2
+			}
+			if len(compactX) >= 4 && levenshtein(compactX, compactY) <= maxDistance {
+				return fmt.Sprintf("similar name %q", y)
+			}
+		}
+	}
+	return ""
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+// checkDuplicatesHandler reports existing projects that may duplicate the posted project
+func (s *Server) checkDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	var project Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %v", err))
+		return
+	}
+
+	duplicates := s.index.FindDuplicates(&project)
+
+	message := "No duplicate projects found"
+	if len(duplicates) > 0 {
+		message = fmt.Sprintf("Found %d possible duplicate project(s)", len(duplicates))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{
+		Message:    message,
+		Duplicates: duplicates,
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import "testing"
+
+func TestURLIdentity(t *testing.T) {
+	tests := []struct {
+		url, host, handle string
+	}{
+		{"https://github.com/ethereum/go-ethereum", "github.com", "ethereum"},
+		{"https://github.com/orgs/ethereum/repositories", "github.com", "orgs/ethereum"},
+		{"https://github.com/orgs", "github.com", ""},
+		{"https://twitter.com/Bebop_DEX", "x.com", "bebop_dex"},
+		{"https://bebop.mirror.xyz/post", "mirror.xyz", "bebop"},
+		{"https://discord.com/invite/AbC", "discord.gg", "abc"},
+		{"https://discord.com/channels/123/456", "discord.gg", "channels/123"},
+		{"https://www.npmjs.com/package/viem", "npmjs.com", "package/viem"},
+		{"https://www.npmjs.com/package", "npmjs.com", ""},
+		{"https://crates.io/crates/alloy", "crates.io", "crates/alloy"},
+		{"https://pypi.org/project/web3/", "pypi.org", "project/web3"},
+		{"https://modules.io/modules/foo", "modules.io", "modules/foo"},
+		{"https://www.linkedin.com/company/bebop", "linkedin.com", "company/bebop"},
+		{"https://www.linkedin.com/in/someone", "linkedin.com", "in/someone"},
+		{"https://www.youtube.com/channel/UC123", "youtube.com", "channel/uc123"},
+		{"https://www.youtube.com/c/Bebop", "youtube.com", "c/bebop"},
+		{"https://www.youtube.com/@bebop", "youtube.com", "bebop"},
+		{"https://docs.google.com/document/d/1AbC/edit", "docs.google.com", "document/d/1abc"},
+		{"https://bebop.xyz/about", "bebop.xyz", "about"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		host, handle := urlIdentity(tt.url)
+		if host != tt.host || handle != tt.handle {
+			t.Errorf("urlIdentity(%q) = %q, %q; want %q, %q", tt.url, host, handle, tt.host, tt.handle)
+		}
+	}
+}
+
+func TestIdentityKeysSharedHosts(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		field string
+		same  bool
+	}{
+		{"npm packages", "https://www.npmjs.com/package/viem", "https://www.npmjs.com/package/ethers", "npm", false},
+		{"same npm package", "https://www.npmjs.com/package/viem", "https://npmjs.com/package/viem/", "npm", true},
+		{"pypi projects", "https://pypi.org/project/web3", "https://pypi.org/project/eth-brownie", "pypi", false},
+		{"crates", "https://crates.io/crates/alloy", "https://crates.io/crates/ethers", "crates", false},
+		{"modules", "https://modules.io/modules/a", "https://modules.io/modules/b", "websites", false},
+		{"linkedin companies", "https://linkedin.com/company/a", "https://linkedin.com/company/b", "linkedin", false},
+		{"youtube channels", "https://youtube.com/channel/a", "https://youtube.com/channel/b", "youtube", false},
+		{"youtube custom urls", "https://youtube.com/c/a", "https://youtube.com/c/b", "youtube", false},
+		{"google docs", "https://docs.google.com/document/d/a/edit", "https://docs.google.com/document/d/b/edit", "websites", false},
+		{"same google doc", "https://docs.google.com/document/d/a/edit", "https://docs.google.com/document/d/a/view", "websites", true},
+		{"discord channels", "https://discord.com/channels/1/2", "https://discord.com/channels/3/4", "discord", false},
+		{"github orgs", "https://github.com/a/x", "https://github.com/a/y", "github", true},
+		{"github org pages", "https://github.com/orgs/a", "https://github.com/orgs/b", "websites", false},
+		{"same github org page", "https://github.com/orgs/a", "https://github.com/orgs/a/people", "websites", true},
+	}
+
+	project := func(field, u string) *Project {
+		urls := []URL{{Url: u}}
+		p := &Project{Social: &Social{}}
+		switch field {
+		case "npm":
+			p.Npm = urls
+		case "pypi":
+			p.Pypi = urls
+		case "crates":
+			p.Crates = urls
+		case "github":
+			p.Github = urls
+		case "linkedin":
+			p.Social.Linkedin = urls
+		case "youtube":
+			p.Social.Youtube = urls
+		case "discord":
+			p.Social.Discord = urls
+		default:
+			p.Websites = urls
+		}
+		return p
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := identityKeys(project(tt.field, tt.a)), identityKeys(project(tt.field, tt.b))
+			shared := false
+			for key := range a {
+				if _, ok := b[key]; ok {
+					shared = true
+				}
+			}
+			if shared != tt.same {
+				t.Errorf("shared identity = %v, want %v (keys %v and %v)", shared, tt.same, a, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// FakeGitHub is an in-process stand-in for the pull request endpoints of the GitHub
+// REST API, so the pull request flow can be tested without touching github.com
+type FakeGitHub struct {
+	URL string
+
+	server   *httptest.Server
+	mutex    sync.Mutex
+	pulls    map[string][]PullRequest    // Opened pull requests by "<owner>/<name>"
+	requests map[string][]NewPullRequest // Request bodies of the opened pull requests
+}
+
+// StartFakeGitHub starts a fake GitHub API on a local port
+func StartFakeGitHub() *FakeGitHub {
+	fg := &FakeGitHub{pulls: make(map[string][]PullRequest), requests: make(map[string][]NewPullRequest)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /repos/{owner}/{repo}/pulls", fg.createPull)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/pulls", fg.listPulls)
+
+	fg.server = httptest.NewServer(mux)
+	fg.URL = fg.server.URL
+	return fg
+}
+
+// Close shuts the fake API down
+func (fg *FakeGitHub) Close() {
+	fg.server.Close()
+}
+
+// PullRequests returns the pull requests opened on a repository
+func (fg *FakeGitHub) PullRequests(repo string) []PullRequest {
+	fg.mutex.Lock()
+	defer fg.mutex.Unlock()
+
+	return append([]PullRequest{}, fg.pulls[repo]...)
+}
+
+// Requests returns the request bodies of the pull requests opened on a repository
+func (fg *FakeGitHub) Requests(repo string) []NewPullRequest {
+	fg.mutex.Lock()
+	defer fg.mutex.Unlock()
+
+	return append([]NewPullRequest{}, fg.requests[repo]...)
+}
+
+// createPull validates a pull request like GitHub does and records it
+func (fg *FakeGitHub) createPull(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeFakeGitHubError(w, http.StatusUnauthorized, "Requires authentication")
+		return
+	}
+
+	var pr NewPullRequest
+	if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+		writeFakeGitHubError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	if pr.Title == "" || pr.Head == "" || pr.Base == "" {
+		writeFakeGitHubError(w, http.StatusUnprocessableEntity, "Validation Failed", "title, head and base are required")
+		return
+	}
+
+	repo := r.PathValue("owner") + "/" + r.PathValue("repo")
+
+	fg.mutex.Lock()
+	defer fg.mutex.Unlock()
+
+	for _, existing := range fg.pulls[repo] {
+		if existing.Head == pr.Head && existing.Base == pr.Base {
+			writeFakeGitHubError(w, http.StatusUnprocessableEntity, "Validation Failed",
+				fmt.Sprintf("A pull request already exists for %s.", pr.Head))
+			return
+		}
+	}
+
+	number := len(fg.pulls[repo]) + 1
+	created := PullRequest{
+		Number: number,
+		URL:    fmt.Sprintf("https://github.com/%s/pull/%d", repo, number),
+		Title:  pr.Title,
+		Head:   pr.Head,
+		Base:   pr.Base,
+	}
+	fg.pulls[repo] = append(fg.pulls[repo], created)
+	fg.requests[repo] = append(fg.requests[repo], pr)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(fakePullJSON(created))
+}
+
+// listPulls returns the pull requests opened on a repository
+func (fg *FakeGitHub) listPulls(w http.ResponseWriter, r *http.Request) {
+	pulls := []map[string]interface{}{}
+	for _, pr := range fg.PullRequests(r.PathValue("owner") + "/" + r.PathValue("repo")) {
+		pulls = append(pulls, fakePullJSON(pr))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pulls)
+}
+
+// fakePullJSON renders a pull request in the shape of the GitHub API
+func fakePullJSON(pr PullRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"number":   pr.Number,
+		"html_url": pr.URL,
+		"title":    pr.Title,
+		"state":    "open",
+		"head":     map[string]interface{}{"label": pr.Head},
+		"base":     map[string]interface{}{"ref": pr.Base},
+	}
+}
+
+// writeFakeGitHubError writes an error body in the format of the GitHub API
+func writeFakeGitHubError(w http.ResponseWriter, status int, message string, details ...string) {
+	body := map[string]interface{}{"message": message}
+	if len(details) > 0 {
+		errs := make([]map[string]string, len(details))
+		for i, detail := range details {
+			errs[i] = map[string]string{"message": detail}
+		}
+		body["errors"] = errs
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Square PNG sizes generated for every raster logo
+var logoSizes = []int{32, 64, 128, 256}
+
+// logoManifestFileName is the file describing the assets in a project's logo directory
+const logoManifestFileName = "logo.json"
+
+// LogoAsset is one generated size of a project logo
+type LogoAsset struct {
+	Size     int    `json:"size"`
+	File     string `json:"file"`
+	Upscaled bool   `json:"upscaled,omitempty"` // The source image was smaller than Size
+}
+
+// LogoManifest describes the stored logo of a project
+type LogoManifest struct {
+	Source   string      `json:"source,omitempty"` // URL the logo was fetched from
+	Format   string      `json:"format"`           // Format of the source image
+	Original string      `json:"original"`         // File holding the full-size logo
+	Width    int         `json:"width,omitempty"`
+	Height   int         `json:"height,omitempty"`
+	Sizes    []LogoAsset `json:"sizes"`
+}
+
+// logoSizeFileName returns the file name of a generated logo size
+func logoSizeFileName(size int) string {
+	return fmt.Sprintf("favicon-%d.png", size)
+}
+
+// logoFileNames lists every file the favicon handler manages in a logo directory
+func logoFileNames() []string {
+	names := []string{faviconFileName, svgLogoFileName, logoManifestFileName}
+	for _, size := range logoSizes {
+		names = append(names, logoSizeFileName(size))
+	}
+	return names
+}
+
+// ParseLogoSize validates a requested logo size
+func ParseLogoSize(value string) (int, error) {
+	size, err := strconv.Atoi(value)
+	if err == nil {
+		for _, allowed := range logoSizes {
+			if size == allowed {
+				return size, nil
+			}
+		}
+	}
+
+	allowed := make([]string, len(logoSizes))
+	for i, s := range logoSizes {
+		allowed[i] = strconv.Itoa(s)
+	}
+	return 0, fmt.Errorf("size must be one of %s", strings.Join(allowed, ", "))
+}
+
+// resizeLogo scales img to fit a size x size square, centring it on a transparent canvas
+func resizeLogo(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	scaledW, scaledH := size, size
+	if width > height {
+		scaledH = max(1, height*size/width)
+	} else if height > width {
+		scaledW = max(1, width*size/height)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	offsetX, offsetY := (size-scaledW)/2, (size-scaledH)/2
+	target := image.Rect(offsetX, offsetY, offsetX+scaledW, offsetY+scaledH)
+	xdraw.CatmullRom.Scale(dst, target, img, bounds, xdraw.Over, nil)
+	return dst
+}
+
+// encodePNG encodes an image as PNG
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeLogoAssets writes the original logo, its resized variants and the manifest
+// into logosDir. SVG logos are scalable, so only the original is stored.
+func writeLogoAssets(logosDir string, favicon *FaviconImage, sourceURL string) (string, error) {
+	data, err := favicon.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	manifest := LogoManifest{
+		Source:   sourceURL,
+		Format:   favicon.Format,
+		Original: faviconFileName,
+		Sizes:    []LogoAsset{},
+	}
+	if favicon.IsSVG() {
+		manifest.Original = svgLogoFileName
+	}
+
+	originalPath := filepath.Join(logosDir, manifest.Original)
+	if err := os.WriteFile(originalPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write favicon file: %v", err)
+	}
+
+	if !favicon.IsSVG() {
+		bounds := favicon.Image.Bounds()
+		manifest.Width, manifest.Height = bounds.Dx(), bounds.Dy()
+
+		for _, size := range logoSizes {
+			resized, err := encodePNG(resizeLogo(favicon.Image, size))
+			if err != nil {
+				return "", err
+			}
+			fileName := logoSizeFileName(size)
+			if err := os.WriteFile(filepath.Join(logosDir, fileName), resized, 0644); err != nil {
+				return "", fmt.Errorf("failed to write %s: %v", fileName, err)
+			}
+			manifest.Sizes = append(manifest.Sizes, LogoAsset{
+				Size:     size,
+				File:     fileName,
+				Upscaled: size > max(manifest.Width, manifest.Height),
+			})
+		}
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode logo manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(logosDir, logoManifestFileName), append(manifestData, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write logo manifest: %v", err)
+	}
+
+	return originalPath, nil
+}
+
+// FindLogoAsset returns the stored logo of a project at the requested size. SVG
+// logos are returned as-is. Logos saved before sizes were generated are resized
+// on the fly. The error satisfies os.IsNotExist if the project has no logo.
+func (fh *FaviconHandler) FindLogoAsset(projectName string, size int) ([]byte, string, error) {
+	originalPath, err := fh.FindFavicon(projectName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if filepath.Ext(originalPath) == ".svg" {
+		data, err := os.ReadFile(originalPath)
+		return data, "image/svg+xml", err
+	}
+
+	if data, err := os.ReadFile(filepath.Join(filepath.Dir(originalPath), logoSizeFileName(size))); err == nil {
+		return data, "image/png", nil
+	}
+
+	original, err := os.ReadFile(originalPath)
+	if err != nil {
+		return nil, "", err
+	}
+	favicon, err := DecodeFavicon(original)
+	if err != nil {
+		return nil, "", fmt.Errorf("stored logo is not a valid image: %v", err)
+	}
+	if favicon.IsSVG() {
+		return nil, "", fmt.Errorf("stored logo %s is not a raster image", originalPath)
+	}
+	data, err := encodePNG(resizeLogo(favicon.Image, size))
+	return data, "image/png", err
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testLogoPNG encodes an opaque red image of the given size
+func testLogoPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decodeTestPNG decodes PNG data and fails the test if it is not a PNG
+func decodeTestPNG(t *testing.T, data []byte) image.Image {
+	t.Helper()
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("not a PNG: %v", err)
+	}
+	return img
+}
+
+func TestParseLogoSize(t *testing.T) {
+	for _, value := range []string{"32", "64", "128", "256"} {
+		if _, err := ParseLogoSize(value); err != nil {
+			t.Errorf("ParseLogoSize(%q) error = %v", value, err)
+		}
+	}
+	for _, value := range []string{"", "0", "16", "512", "64px", "-32"} {
+		if _, err := ParseLogoSize(value); err == nil || err.Error() != "size must be one of 32, 64, 128, 256" {
+			t.Errorf("ParseLogoSize(%q) error = %v", value, err)
+		}
+	}
+}
+
+func TestResizeLogoKeepsAspectRatio(t *testing.T) {
+	wide := decodeTestPNG(t, testLogoPNG(t, 100, 50))
+
+	resized := resizeLogo(wide, 64)
+	if bounds := resized.Bounds(); bounds.Dx() != 64 || bounds.Dy() != 64 {
+		t.Fatalf("resized bounds = %v, want 64x64", bounds)
+	}
+
+	// The 64x32 image is centred vertically on a transparent canvas
+	alpha := func(x, y int) uint8 {
+		return color.NRGBAModel.Convert(resized.At(x, y)).(color.NRGBA).A
+	}
+	for _, point := range []image.Point{{32, 0}, {32, 15}, {32, 48}, {32, 63}} {
+		if a := alpha(point.X, point.Y); a != 0 {
+			t.Errorf("pixel %v alpha = %d, want transparent", point, a)
+		}
+	}
+	for _, point := range []image.Point{{0, 32}, {32, 17}, {63, 46}} {
+		if a := alpha(point.X, point.Y); a != 255 {
+			t.Errorf("pixel %v alpha = %d, want opaque", point, a)
+		}
+	}
+}
+
+func TestSaveFaviconWritesLogoSizes(t *testing.T) {
+	dir := t.TempDir()
+	fh := NewFaviconHandler(dir)
+
+	if _, err := fh.SaveFavicon("bebop", testLogoPNG(t, 100, 40), "https://bebop.xyz/icon.png"); err != nil {
+		t.Fatalf("SaveFavicon() error = %v", err)
+	}
+	logosDir := filepath.Join(dir, logoRelDir("bebop"))
+
+	for _, size := range logoSizes {
+		data, err := os.ReadFile(filepath.Join(logosDir, logoSizeFileName(size)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bounds := decodeTestPNG(t, data).Bounds(); bounds.Dx() != size || bounds.Dy() != size {
+			t.Errorf("%s is %v, want %dx%d", logoSizeFileName(size), bounds, size, size)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(logosDir, logoManifestFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest LogoManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	want := LogoManifest{
+		Source:   "https://bebop.xyz/icon.png",
+		Format:   "png",
+		Original: faviconFileName,
+		Width:    100,
+		Height:   40,
+		Sizes: []LogoAsset{
+			{Size: 32, File: "favicon-32.png"},
+			{Size: 64, File: "favicon-64.png"},
+			{Size: 128, File: "favicon-128.png", Upscaled: true},
+			{Size: 256, File: "favicon-256.png", Upscaled: true},
+		},
+	}
+	if !reflect.DeepEqual(manifest, want) {
+		t.Errorf("manifest = %+v, want %+v", manifest, want)
+	}
+
+	// Saving an SVG replaces the raster set
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><rect width="10" height="10"/></svg>`)
+	if _, err := fh.SaveFavicon("bebop", svg, ""); err != nil {
+		t.Fatalf("SaveFavicon(svg) error = %v", err)
+	}
+	entries, err := os.ReadDir(logosDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !reflect.DeepEqual(names, []string{logoManifestFileName, svgLogoFileName}) {
+		t.Errorf("logo directory = %q, want only the SVG and its manifest", names)
+	}
+}
+
+func TestFindLogoAsset(t *testing.T) {
+	dir := t.TempDir()
+	fh := NewFaviconHandler(dir)
+	if _, err := fh.SaveFavicon("bebop", testLogoPNG(t, 300, 300), ""); err != nil {
+		t.Fatal(err)
+	}
+	logosDir := filepath.Join(dir, logoRelDir("bebop"))
+
+	data, contentType, err := fh.FindLogoAsset("bebop", 64)
+	if err != nil || contentType != "image/png" {
+		t.Fatalf("FindLogoAsset(64) = %s, %v", contentType, err)
+	}
+	stored, _ := os.ReadFile(filepath.Join(logosDir, logoSizeFileName(64)))
+	if !bytes.Equal(data, stored) {
+		t.Error("FindLogoAsset(64) did not return the stored size")
+	}
+
+	// Logos saved before sizes were generated are resized on request
+	if err := os.Remove(filepath.Join(logosDir, logoSizeFileName(128))); err != nil {
+		t.Fatal(err)
+	}
+	data, _, err = fh.FindLogoAsset("bebop", 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := decodeTestPNG(t, data).Bounds(); bounds.Dx() != 128 || bounds.Dy() != 128 {
+		t.Errorf("resized logo is %v, want 128x128", bounds)
+	}
+
+	if _, _, err := fh.FindLogoAsset("gamma", 64); !os.IsNotExist(err) {
+		t.Errorf("FindLogoAsset(gamma) error = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for the favicon backfill job
+const (
+	defaultBackfillWorkers      = 4
+	maxBackfillWorkers          = 16
+	defaultBackfillHostInterval = 2 * time.Second
+)
+
+// Backfill job states
+const (
+	backfillRunning  = "running"
+	backfillFinished = "finished"
+)
+
+// BackfillOptions tunes how aggressively the backfill job fetches favicons
+type BackfillOptions struct {
+	Workers      int           // Number of concurrent fetches
+	HostInterval time.Duration // Minimum delay between fetches from the same host
+}
+
+// BackfillFailure records a project whose favicon could not be backfilled
+type BackfillFailure struct {
+	Project string `json:"project"`
+	Website string `json:"website,omitempty"`
+	Error   string `json:"error"`
+}
+
+// BackfillStatus is a snapshot of a backfill job's progress
+type BackfillStatus struct {
+	ID         string            `json:"id"`
+	State      string            `json:"state"`
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+	Total      int               `json:"total"`     // Projects without a logo directory
+	Processed  int               `json:"processed"` // Projects attempted so far
+	Saved      []string          `json:"saved"`     // Paths of the favicons written
+	Skipped    []string          `json:"skipped"`   // Projects without a website
+	Failures   []BackfillFailure `json:"failures"`
+}
+
+// BackfillJob fetches favicons for every project that has no logo yet
+type BackfillJob struct {
+	favicons *FaviconHandler
+	options  BackfillOptions
+	limiter  *hostLimiter
+
+	mutex  sync.Mutex
+	status BackfillStatus
+}
+
+// NewBackfillJob prepares a backfill job for the given projects. Projects that
+// already have a data/logos/<slug>/ directory are left out.
+func NewBackfillJob(favicons *FaviconHandler, projects []*IndexedProject, options BackfillOptions) (*BackfillJob, []*Project) {
+	if options.Workers <= 0 {
+		options.Workers = defaultBackfillWorkers
+	}
+	options.Workers = min(options.Workers, maxBackfillWorkers)
+
+	var missing []*Project
+	for _, entry := range projects {
+		logosDir := filepath.Dir(favicons.GetFaviconPath(entry.Project.Name))
+		if _, err := os.Stat(logosDir); os.IsNotExist(err) {
+			missing = append(missing, entry.Project)
+		}
+	}
+
+	now := time.Now()
+	job := &BackfillJob{
+		favicons: favicons,
+		options:  options,
+		limiter:  newHostLimiter(options.HostInterval),
+		status: BackfillStatus{
+			ID:        fmt.Sprintf("backfill-%d", now.Unix()),
+			State:     backfillRunning,
+			StartedAt: now,
+			Total:     len(missing),
+			Saved:     []string{},
+			Skipped:   []string{},
+			Failures:  []BackfillFailure{},
+		},
+	}
+	return job, missing
+}
+
+// Run fetches and saves the favicons of projects with a bounded worker pool and
+// returns the final status
+func (job *BackfillJob) Run(projects []*Project) BackfillStatus {
+	queue := make(chan *Project)
+	var wg sync.WaitGroup
+
+	for i := 0; i < job.options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for project := range queue {
+				job.backfill(project)
+			}
+		}()
+	}
+
+	for _, project := range projects {
+		queue <- project
+	}
+	close(queue)
+	wg.Wait()
+
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	finished := time.Now()
+	job.status.State = backfillFinished
+	job.status.FinishedAt = &finished
+	return job.snapshot()
+}
+
+// backfill fetches and saves the favicon of one project
+func (job *BackfillJob) backfill(project *Project) {
+	website := ""
+	if len(project.Websites) > 0 {
+		website = strings.TrimSpace(project.Websites[0].Url)
+	}
+	if website == "" {
+		job.finish(func(status *BackfillStatus) {
+			status.Skipped = append(status.Skipped, project.Name)
+		})
+		return
+	}
+
+	job.limiter.Wait(website)
+
+	data, sourceURL, err := job.favicons.FetchFavicon(website, FetchOptions{})
+	var faviconPath string
+	if err == nil {
+		faviconPath, err = job.favicons.SaveFavicon(project.Name, data, sourceURL)
+	}
+
+	if err != nil {
+		log.Printf("Backfill: no favicon for %s: %v", project.Name, err)
+		job.finish(func(status *BackfillStatus) {
+			status.Failures = append(status.Failures, BackfillFailure{
+				Project: project.Name,
+				Website: website,
+				Error:   err.Error(),
+			})
+		})
+		return
+	}
+
+	log.Printf("Backfill: saved %s", faviconPath)
+	job.finish(func(status *BackfillStatus) {
+		status.Saved = append(status.Saved, faviconPath)
+	})
+}
+
+// finish records the outcome of one project
+func (job *BackfillJob) finish(update func(*BackfillStatus)) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	job.status.Processed++
+	update(&job.status)
+}
+
+// Status returns a snapshot of the job's progress
+func (job *BackfillJob) Status() BackfillStatus {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	return job.snapshot()
+}
+
+// snapshot copies the status. The caller must hold the mutex.
+func (job *BackfillJob) snapshot() BackfillStatus {
+	status := job.status
+	status.Saved = append([]string{}, job.status.Saved...)
+	status.Skipped = append([]string{}, job.status.Skipped...)
+	status.Failures = append([]BackfillFailure{}, job.status.Failures...)
+	return status
+}
+
+// hostLimiter spaces out requests to the same host
+type hostLimiter struct {
+	interval time.Duration
+
+	mutex sync.Mutex
+	next  map[string]time.Time
+}
+
+func newHostLimiter(interval time.Duration) *hostLimiter {
+	return &hostLimiter{interval: interval, next: make(map[string]time.Time)}
+}
+
+// Wait blocks until a request to the host of rawURL may be made
+func (hl *hostLimiter) Wait(rawURL string) {
+	host, _ := urlIdentity(rawURL)
+	if host == "" || hl.interval <= 0 {
+		return
+	}
+
+	hl.mutex.Lock()
+	now := time.Now()
+	slot := hl.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	hl.next[host] = slot.Add(hl.interval)
+	hl.mutex.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
+
+// stageBackfill stages the logo directories written by a backfill job
+func (s *Server) stageBackfill(status BackfillStatus) error {
+	if len(status.Saved) == 0 {
+		return nil
+	}
+
+	var dirs []string
+	for _, faviconPath := range status.Saved {
+		dirs = append(dirs, filepath.Dir(faviconPath))
+		s.recordAction(faviconPath, actionFaviconSaved)
+	}
+	return s.stageFiles(dirs...)
+}
+
+// startBackfill starts a backfill job in the background unless one is already running
+func (s *Server) startBackfill(options BackfillOptions) (*BackfillJob, error) {
+	s.backfillMutex.Lock()
+	defer s.backfillMutex.Unlock()
+
+	if s.backfill != nil && s.backfill.Status().State == backfillRunning {
+		return nil, fmt.Errorf("backfill job %s is already running", s.backfill.Status().ID)
+	}
+
+	job, projects := NewBackfillJob(s.favicons, s.index.All(), options)
+	s.backfill = job
+
+	go func() {
+		status := job.Run(projects)
+		log.Printf("Backfill %s finished: %d saved, %d skipped, %d failed",
+			status.ID, len(status.Saved), len(status.Skipped), len(status.Failures))
+		if err := s.stageBackfill(status); err != nil {
+			log.Printf("Warning: Failed to stage backfilled favicons: %v", err)
+		}
+	}()
+
+	return job, nil
+}
+
+// backfillFaviconsHandler starts a favicon backfill job for every project without a logo.
+// The optional workers parameter sets the size of the worker pool.
+func (s *Server) backfillFaviconsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	options := BackfillOptions{HostInterval: defaultBackfillHostInterval}
+	if param := r.URL.Query().Get("workers"); param != "" {
+		workers, err := strconv.Atoi(param)
+		if err != nil || workers < 1 || workers > maxBackfillWorkers {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("workers must be between 1 and %d", maxBackfillWorkers))
+			return
+		}
+		options.Workers = workers
+	}
+
+	job, err := s.startBackfill(options)
+	if err != nil {
+		writeErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job.Status())
+}
+
+// backfillStatusHandler reports the progress of the current or last backfill job
+func (s *Server) backfillStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	s.backfillMutex.Lock()
+	job := s.backfill
+	s.backfillMutex.Unlock()
+
+	if job == nil {
+		writeErrorResponse(w, http.StatusNotFound, "No backfill job has been started")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.Status())
+}
+
+// runBackfillCommand implements the backfill-favicons subcommand, which runs a
+// backfill job in the foreground and stages the fetched logos
+func runBackfillCommand(args []string) {
+	fs := flag.NewFlagSet("backfill-favicons", flag.ContinueOnError)
+	workers := fs.Int("workers", defaultBackfillWorkers, "number of concurrent favicon fetches")
+	hostInterval := fs.Duration("host-interval", defaultBackfillHostInterval, "minimum delay between fetches from the same host")
+
+	cfg, err := LoadConfigFlags(fs, args)
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if *workers < 1 || *workers > maxBackfillWorkers {
+		log.Fatalf("-workers must be between 1 and %d", maxBackfillWorkers)
+	}
+
+	s, err := NewServer(cfg)
+	if err != nil {
+		log.Fatalf("Error initializing server: %v", err)
+	}
+	s.rebuildIndex()
+
+	job, projects := NewBackfillJob(s.favicons, s.index.All(), BackfillOptions{
+		Workers:      *workers,
+		HostInterval: *hostInterval,
+	})
+	log.Printf("Backfilling favicons for %d project(s) without a logo", len(projects))
+
+	status := job.Run(projects)
+	if err := s.stageBackfill(status); err != nil {
+		log.Printf("Warning: Failed to stage backfilled favicons: %v", err)
+	}
+
+	fmt.Printf("Saved %d favicon(s), skipped %d project(s) without a website, %d failure(s)\n",
+		len(status.Saved), len(status.Skipped), len(status.Failures))
+	for _, failure := range status.Failures {
+		fmt.Printf("  %s (%s): %s\n", failure.Project, failure.Website, failure.Error)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	const interval = 50 * time.Millisecond
+
+	t.Run("same host is spaced out", func(t *testing.T) {
+		limiter := newHostLimiter(interval)
+		start := time.Now()
+
+		var wg sync.WaitGroup
+		for _, rawURL := range []string{"https://bebop.xyz", "https://www.bebop.xyz/about", "http://BEBOP.xyz:8080"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				limiter.Wait(rawURL)
+			}()
+		}
+		wg.Wait()
+
+		if elapsed := time.Since(start); elapsed < 2*interval {
+			t.Errorf("three requests to one host took %v, want at least %v", elapsed, 2*interval)
+		}
+	})
+
+	t.Run("different hosts do not wait", func(t *testing.T) {
+		limiter := newHostLimiter(time.Hour)
+		start := time.Now()
+		for _, rawURL := range []string{"https://alpha.xyz", "https://bebop.xyz", "https://gamma.xyz"} {
+			limiter.Wait(rawURL)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("requests to different hosts took %v", elapsed)
+		}
+	})
+
+	t.Run("no interval or no host", func(t *testing.T) {
+		start := time.Now()
+		newHostLimiter(0).Wait("https://bebop.xyz")
+		newHostLimiter(0).Wait("https://bebop.xyz")
+		limiter := newHostLimiter(time.Hour)
+		limiter.Wait("")
+		limiter.Wait("")
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("unlimited requests took %v", elapsed)
+		}
+	})
+
+	t.Run("slots free up over time", func(t *testing.T) {
+		limiter := newHostLimiter(interval)
+		limiter.Wait("https://bebop.xyz")
+		time.Sleep(2 * interval)
+
+		start := time.Now()
+		limiter.Wait("https://bebop.xyz")
+		if elapsed := time.Since(start); elapsed >= interval {
+			t.Errorf("a request after the interval waited %v", elapsed)
+		}
+	})
+}
+
+func TestNewBackfillJob(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "data/logos/alpha/favicon.png", "png")
+
+	projects := []*IndexedProject{
+		{Project: &Project{Name: "alpha"}},
+		{Project: &Project{Name: "bebop"}},
+		{Project: &Project{Name: "gamma"}},
+	}
+
+	tests := []struct {
+		workers int
+		want    int
+	}{
+		{0, defaultBackfillWorkers},
+		{-1, defaultBackfillWorkers},
+		{3, 3},
+		{100, maxBackfillWorkers},
+	}
+	for _, tt := range tests {
+		job, missing := NewBackfillJob(NewFaviconHandler(dir), projects, BackfillOptions{Workers: tt.workers})
+		if job.options.Workers != tt.want {
+			t.Errorf("workers %d = %d, want %d", tt.workers, job.options.Workers, tt.want)
+		}
+		if len(missing) != 2 || missing[0].Name != "bebop" || missing[1].Name != "gamma" {
+			t.Errorf("missing = %+v, want bebop and gamma", missing)
+		}
+		if status := job.Status(); status.State != backfillRunning || status.Total != 2 {
+			t.Errorf("status = %+v", status)
+		}
+	}
+}
+
+func TestBackfillJobRun(t *testing.T) {
+	dir := t.TempDir()
+	job, missing := NewBackfillJob(NewFaviconHandler(dir), []*IndexedProject{
+		{Project: &Project{Name: "alpha"}},
+		{Project: &Project{Name: "bebop", Websites: []URL{{Url: " "}}}},
+		{Project: &Project{Name: "gamma", Websites: []URL{{Url: "http://127.0.0.1:1/"}}}},
+	}, BackfillOptions{Workers: 2})
+
+	status := job.Run(missing)
+	if status.State != backfillFinished || status.FinishedAt == nil || status.Processed != 3 {
+		t.Errorf("status = %+v", status)
+	}
+	sort.Strings(status.Skipped)
+	if !reflect.DeepEqual(status.Skipped, []string{"alpha", "bebop"}) {
+		t.Errorf("skipped = %q, want the projects without a website", status.Skipped)
+	}
+	if len(status.Failures) != 1 || status.Failures[0].Project != "gamma" || status.Failures[0].Website != "http://127.0.0.1:1/" {
+		t.Errorf("failures = %+v", status.Failures)
+	}
+	if len(status.Saved) != 0 {
+		t.Errorf("saved = %q", status.Saved)
+	}
+}
+
+func TestBackfillHandlers(t *testing.T) {
+	s := newTestServer(t, false)
+	writeTestFile(t, s.config.DirectoryPath, projectRelPath("alpha"), "name: alpha\ndisplay_name: Alpha\n")
+	s.rebuildIndex()
+
+	recorder := httptest.NewRecorder()
+	s.backfillStatusHandler(recorder, httptest.NewRequest(http.MethodGet, "/backfillStatus", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("status before a job = %d, want 404", recorder.Code)
+	}
+
+	for _, workers := range []string{"0", "17", "many"} {
+		recorder := httptest.NewRecorder()
+		s.backfillFaviconsHandler(recorder, httptest.NewRequest(http.MethodPost, "/backfillFavicons?workers="+workers, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("workers=%s status = %d, want 400", workers, recorder.Code)
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	s.backfillFaviconsHandler(recorder, httptest.NewRequest(http.MethodPost, "/backfillFavicons?workers=2", nil))
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("start status = %d (%s), want 202", recorder.Code, recorder.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.backfill.Status().State == backfillRunning {
+		if time.Now().After(deadline) {
+			t.Fatal("the backfill job did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	recorder = httptest.NewRecorder()
+	s.backfillStatusHandler(recorder, httptest.NewRequest(http.MethodGet, "/backfillStatus", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("status after the job = %d, want 200", recorder.Code)
+	}
+	if status := s.backfill.Status(); !reflect.DeepEqual(status.Skipped, []string{"alpha"}) {
+		t.Errorf("skipped = %q, want alpha", status.Skipped)
+	}
+}
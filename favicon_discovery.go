@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// IconCandidate is an icon advertised by a web page, its web app manifest or the
+// conventional /favicon.ico location
+type IconCandidate struct {
+	URL    string // Absolute URL of the icon
+	Rel    string // Link relation, "manifest" or "default"
+	Type   string // Declared MIME type, if any
+	Size   int    // Largest declared dimension in pixels, 0 if unknown
+	AnyDim bool   // Declared with sizes="any" (scalable)
+}
+
+// Link relations that advertise icons
+var iconRels = map[string]bool{
+	"icon":                         true,
+	"shortcut":                     true,
+	"apple-touch-icon":             true,
+	"apple-touch-icon-precomposed": true,
+	"mask-icon":                    true,
+	"fluid-icon":                   true,
+}
+
+// PageIcons is the result of scanning an HTML document for icons
+type PageIcons struct {
+	Candidates  []IconCandidate
+	ManifestURL string // Absolute URL of the web app manifest, if linked
+}
+
+// DiscoverPageIcons parses an HTML document and collects every icon link, resolving
+// relative URLs against <base href> and the final (post-redirect) page URL
+func DiscoverPageIcons(body io.Reader, pageURL *url.URL) (*PageIcons, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	base := pageURL
+	var links []*html.Node
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "base":
+				// Only the first <base href> counts
+				if href := htmlAttr(n, "href"); href != "" && base == pageURL {
+					if resolved, err := pageURL.Parse(href); err == nil {
+						base = resolved
+					}
+				}
+			case "link":
+				links = append(links, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	icons := &PageIcons{}
+	for _, link := range links {
+		href := strings.TrimSpace(htmlAttr(link, "href"))
+		if href == "" || strings.HasPrefix(href, "data:") {
+			continue
+		}
+		resolved, err := base.Parse(href)
+		if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+			continue
+		}
+
+		rels := strings.Fields(strings.ToLower(htmlAttr(link, "rel")))
+		if containsRel(rels, "manifest") {
+			if icons.ManifestURL == "" {
+				icons.ManifestURL = resolved.String()
+			}
+			continue
+		}
+
+		rel := iconRel(rels)
+		if rel == "" {
+			continue
+		}
+
+		size, anyDim := parseIconSizes(htmlAttr(link, "sizes"))
+		icons.Candidates = append(icons.Candidates, IconCandidate{
+			URL:    resolved.String(),
+			Rel:    rel,
+			Type:   strings.ToLower(htmlAttr(link, "type")),
+			Size:   size,
+			AnyDim: anyDim,
+		})
+	}
+
+	return icons, nil
+}
+
+// ParseManifestIcons reads the icons of a web app manifest, resolving them
+// relative to the manifest URL
+func ParseManifestIcons(data []byte, manifestURL *url.URL) []IconCandidate {
+	var manifest struct {
+		Icons []struct {
+			Src     string `json:"src"`
+			Sizes   string `json:"sizes"`
+			Type    string `json:"type"`
+			Purpose string `json:"purpose"`
+		} `json:"icons"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil
+	}
+
+	var candidates []IconCandidate
+	for _, icon := range manifest.Icons {
+		// Monochrome icons are silhouettes, not logos
+		if strings.Contains(icon.Purpose, "monochrome") && !strings.Contains(icon.Purpose, "any") {
+			continue
+		}
+		resolved, err := manifestURL.Parse(strings.TrimSpace(icon.Src))
+		if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+			continue
+		}
+		size, anyDim := parseIconSizes(icon.Sizes)
+		candidates = append(candidates, IconCandidate{
+			URL:    resolved.String(),
+			Rel:    "manifest",
+			Type:   strings.ToLower(icon.Type),
+			Size:   size,
+			AnyDim: anyDim,
+		})
+	}
+	return candidates
+}
+
+// DefaultIconCandidate returns the conventional /favicon.ico location for a site
+func DefaultIconCandidate(siteURL *url.URL) IconCandidate {
+	faviconURL := &url.URL{Scheme: siteURL.Scheme, Host: siteURL.Host, Path: "/favicon.ico"}
+	return IconCandidate{URL: faviconURL.String(), Rel: "default", Type: "image/x-icon"}
+}
+
+// RankIconCandidates orders candidates from best to worst logo and drops duplicate URLs
+func RankIconCandidates(candidates []IconCandidate) []IconCandidate {
+	seen := make(map[string]bool)
+	var unique []IconCandidate
+	for _, candidate := range candidates {
+		if seen[candidate.URL] {
+			continue
+		}
+		seen[candidate.URL] = true
+		unique = append(unique, candidate)
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		return iconScore(unique[i]) > iconScore(unique[j])
+	})
+	return unique
+}
+
+// iconScore rates how suitable a candidate is as a project logo
+func iconScore(c IconCandidate) int {
+	switch c.Rel {
+	case "mask-icon":
+		return 1 // Single-colour Safari pinned tab icon
+	case "default":
+		return 2 // Only a guess, try it last
+	}
+
+	if c.Type == "image/svg+xml" || strings.HasSuffix(strings.ToLower(path.Ext(urlPath(c.URL))), ".svg") || c.AnyDim {
+		return 1000
+	}
+
+	size := c.Size
+	if size == 0 {
+		// Undeclared sizes: touch icons are conventionally 180px, plain icons tiny
+		switch {
+		case strings.HasPrefix(c.Rel, "apple-touch-icon"):
+			size = 180
+		case strings.HasSuffix(strings.ToLower(urlPath(c.URL)), ".ico"):
+			size = 16
+		default:
+			size = 32
+		}
+	}
+
+	// Scale down oversized images so a 512px PNG does not beat a vector logo
+	if size > 999 {
+		size = 999
+	}
+	return size
+}
+
+// parseIconSizes returns the largest dimension of a sizes attribute like "16x16 32x32"
+func parseIconSizes(sizes string) (int, bool) {
+	largest, anyDim := 0, false
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		if size == "any" {
+			anyDim = true
+			continue
+		}
+		dims := strings.SplitN(size, "x", 2)
+		if len(dims) != 2 {
+			continue
+		}
+		width, errW := strconv.Atoi(dims[0])
+		height, errH := strconv.Atoi(dims[1])
+		if errW != nil || errH != nil {
+			continue
+		}
+		largest = max(largest, width, height)
+	}
+	return largest, anyDim
+}
+
+// iconRel returns the icon relation of a link, or "" if it does not advertise an icon
+func iconRel(rels []string) string {
+	for _, rel := range rels {
+		if iconRels[rel] {
+			if rel == "shortcut" {
+				return "icon"
+			}
+			return rel
+		}
+	}
+	return ""
+}
+
+func containsRel(rels []string, rel string) bool {
+	for _, r := range rels {
+		if r == rel {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlAttr returns the value of an attribute of an HTML element
+func htmlAttr(n *html.Node, name string) string {
+	for _, attr := range n.Attr {
+		if strings.EqualFold(attr.Key, name) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// urlPath returns the path component of a URL string
+func urlPath(raw string) string {
+	if parsed, err := url.Parse(raw); err == nil {
+		return parsed.Path
+	}
+	return raw
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverPageIcons(t *testing.T) {
+	page := `<!doctype html>
+<html><head>
+<base href="/static/">
+<base href="https://ignored.example/">
+<link rel="icon" href="favicon-32.png" sizes="32x32" type="image/png">
+<link rel="shortcut icon" href="/favicon.ico">
+<link rel="Apple-Touch-Icon" href="https://cdn.example.org/touch.png" sizes="180x180">
+<link rel="mask-icon" href="pinned.svg">
+<link rel="icon" href="data:image/png;base64,AAAA">
+<link rel="icon" href="javascript:alert(1)">
+<link rel="icon" href="">
+<link rel="stylesheet" href="site.css">
+<link rel="manifest" href="/site.webmanifest">
+<link rel="manifest" href="/second.webmanifest">
+</head><body><link rel="icon" href="logo.svg" sizes="any" type="image/svg+xml"></body></html>`
+
+	pageURL, _ := url.Parse("https://example.org/docs/index.html")
+	icons, err := DiscoverPageIcons(strings.NewReader(page), pageURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []IconCandidate{
+		{URL: "https://example.org/static/favicon-32.png", Rel: "icon", Type: "image/png", Size: 32},
+		{URL: "https://example.org/favicon.ico", Rel: "icon"},
+		{URL: "https://cdn.example.org/touch.png", Rel: "apple-touch-icon", Size: 180},
+		{URL: "https://example.org/static/pinned.svg", Rel: "mask-icon"},
+		{URL: "https://example.org/static/logo.svg", Rel: "icon", Type: "image/svg+xml", AnyDim: true},
+	}
+	if !reflect.DeepEqual(icons.Candidates, want) {
+		t.Errorf("candidates =\n%+v\nwant\n%+v", icons.Candidates, want)
+	}
+	if icons.ManifestURL != "https://example.org/site.webmanifest" {
+		t.Errorf("manifest URL = %q", icons.ManifestURL)
+	}
+}
+
+func TestParseManifestIcons(t *testing.T) {
+	manifest := `{"icons": [
+		{"src": "icons/192.png", "sizes": "192x192", "type": "image/png"},
+		{"src": "/icons/512.png", "sizes": "512x512", "type": "image/PNG", "purpose": "any maskable"},
+		{"src": "icons/mono.png", "sizes": "512x512", "purpose": "monochrome"},
+		{"src": "icons/mono-any.png", "sizes": "96x96", "purpose": "monochrome any"},
+		{"src": "ftp://example.org/icon.png"}
+	]}`
+
+	manifestURL, _ := url.Parse("https://example.org/app/site.webmanifest")
+	got := ParseManifestIcons([]byte(manifest), manifestURL)
+	want := []IconCandidate{
+		{URL: "https://example.org/app/icons/192.png", Rel: "manifest", Type: "image/png", Size: 192},
+		{URL: "https://example.org/icons/512.png", Rel: "manifest", Type: "image/png", Size: 512},
+		{URL: "https://example.org/app/icons/mono-any.png", Rel: "manifest", Size: 96},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseManifestIcons() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := ParseManifestIcons([]byte("not json"), manifestURL); got != nil {
+		t.Errorf("ParseManifestIcons(invalid) = %+v, want nil", got)
+	}
+}
+
+func TestRankIconCandidates(t *testing.T) {
+	site, _ := url.Parse("https://example.org/docs/")
+	candidates := []IconCandidate{
+		DefaultIconCandidate(site),
+		{URL: "https://example.org/favicon-16.ico", Rel: "icon"},
+		{URL: "https://example.org/pinned.svg", Rel: "mask-icon"},
+		{URL: "https://example.org/icon-32.png", Rel: "icon"},
+		{URL: "https://example.org/touch.png", Rel: "apple-touch-icon"},
+		{URL: "https://example.org/icons/512.png", Rel: "manifest", Size: 512},
+		{URL: "https://example.org/huge.png", Rel: "icon", Size: 4096},
+		{URL: "https://example.org/logo.svg?v=2", Rel: "icon"},
+		{URL: "https://example.org/icon-32.png", Rel: "icon", Size: 256}, // Duplicate URL
+	}
+
+	var got []string
+	for _, candidate := range RankIconCandidates(candidates) {
+		got = append(got, candidate.URL)
+	}
+	want := []string{
+		"https://example.org/logo.svg?v=2",  // Vector
+		"https://example.org/huge.png",      // Capped below vectors
+		"https://example.org/icons/512.png", // Declared size
+		"https://example.org/touch.png",     // Touch icons default to 180px
+		"https://example.org/icon-32.png",   // First of the duplicates, 32px by default
+		"https://example.org/favicon-16.ico",
+		"https://example.org/favicon.ico", // Conventional location, a guess
+		"https://example.org/pinned.svg",  // Single-colour
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RankIconCandidates() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseIconSizes(t *testing.T) {
+	tests := []struct {
+		sizes  string
+		size   int
+		anyDim bool
+	}{
+		{"", 0, false},
+		{"16x16", 16, false},
+		{"16x16 48X48 32x32", 48, false},
+		{"64x128", 128, false},
+		{"any", 0, true},
+		{"any 192x192", 192, true},
+		{"big 12 axb", 0, false},
+	}
+
+	for _, tt := range tests {
+		if size, anyDim := parseIconSizes(tt.sizes); size != tt.size || anyDim != tt.anyDim {
+			t.Errorf("parseIconSizes(%q) = %d, %v; want %d, %v", tt.sizes, size, anyDim, tt.size, tt.anyDim)
+		}
+	}
+}
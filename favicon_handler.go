@@ -1,16 +1,21 @@
 package main
 
 import (
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
-	"time"
+)
+
+// File names used for stored project logos
+const (
+	faviconFileName = "favicon.png"
+	svgLogoFileName = "logo.svg"
 )
 
 // FaviconHandler manages favicon operations for projects
@@ -25,38 +30,61 @@ func NewFaviconHandler(baseDir string) *FaviconHandler {
 	}
 }
 
-// FetchFavicon gets a favicon from a website URL
-func (fh *FaviconHandler) FetchFavicon(url string) ([]byte, error) {
-	if url == "" {
-		return nil, errors.New("URL cannot be empty")
+// Limits on how much of a page or icon is read while looking for a favicon
+const (
+	maxPageBytes     = 2 << 20
+	maxManifestBytes = 512 << 10
+	maxFaviconBytes  = 5 << 20
+)
+
+// FetchFavicon gets the best logo advertised by a website. The page is parsed for
+// icon links and its web app manifest, candidates are ranked by declared size and
+// format, and the first one that downloads as a real image is returned.
+// The URL the icon was downloaded from is returned alongside its data. All requests
+// go through the hardened fetch client, see NewFetchClient.
+func (fh *FaviconHandler) FetchFavicon(siteURL string, opts FetchOptions) ([]byte, string, error) {
+	if siteURL == "" {
+		return nil, "", errors.New("URL cannot be empty")
 	}
 
 	// Ensure URL has a scheme
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		url = "https://" + url
+	if !strings.HasPrefix(siteURL, "http://") && !strings.HasPrefix(siteURL, "https://") {
+		siteURL = "https://" + siteURL
 	}
 
-	// Create a custom HTTP client with relaxed TLS settings for testing
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	pageURL, err := url.Parse(siteURL)
+	if err != nil || pageURL.Host == "" {
+		return nil, "", fmt.Errorf("invalid URL %q", siteURL)
 	}
-	client := &http.Client{
-		Transport: tr,
-		Timeout:   time.Second * 10,
+
+	client := NewFetchClient(opts)
+
+	candidates, err := fh.discoverIcons(client, pageURL)
+	if err != nil {
+		// The page may be unreachable while /favicon.ico still works
+		log.Printf("Warning: icon discovery failed for %s: %v", siteURL, err)
+		candidates = []IconCandidate{DefaultIconCandidate(pageURL)}
 	}
 
-	// Try direct favicon.ico path first
-	parsedURL := strings.TrimSuffix(url, "/")
-	faviconURL := fmt.Sprintf("%s/favicon.ico", parsedURL)
+	var lastErr error
+	for _, candidate := range candidates {
+		data, err := fetchIcon(client, candidate.URL)
+		if err == nil {
+			return data, candidate.URL, nil
+		}
+		lastErr = err
+	}
 
-	resp, err := client.Get(faviconURL)
-	if err == nil && resp.StatusCode == http.StatusOK {
-		defer resp.Body.Close()
-		return io.ReadAll(resp.Body)
+	if lastErr == nil {
+		return nil, "", fmt.Errorf("no favicon found for %s", siteURL)
 	}
+	return nil, "", fmt.Errorf("no usable favicon found for %s: %v", siteURL, lastErr)
+}
 
-	// If direct path fails, try to get the web page and parse for favicon
-	resp, err = client.Get(url)
+// discoverIcons fetches a page and returns its icon candidates, best first. Relative
+// icon URLs are resolved against the final URL after redirects.
+func (fh *FaviconHandler) discoverIcons(client *http.Client, pageURL *url.URL) ([]IconCandidate, error) {
+	resp, err := client.Get(pageURL.String())
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch website: %v", err)
 	}
@@ -66,96 +94,138 @@ func (fh *FaviconHandler) FetchFavicon(url string) ([]byte, error) {
 		return nil, fmt.Errorf("failed to fetch website, status code: %d", resp.StatusCode)
 	}
 
-	// Read the body content
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+	if err := checkContentType(resp, htmlContentTypes); err != nil {
+		return nil, fmt.Errorf("website is not an HTML page: %v", err)
 	}
 
-	// Extract favicon link from HTML
-	bodyStr := string(body)
-	re := regexp.MustCompile(`<link[^>]*rel=["'](?:shortcut )?icon["'][^>]*href=["']([^"']+)["'][^>]*>`)
-	matches := re.FindStringSubmatch(bodyStr)
-
-	if len(matches) < 2 {
-		return nil, fmt.Errorf("no favicon found in the HTML")
+	// Icon links live in the head, so a truncated page is still useful
+	finalURL := resp.Request.URL
+	icons, err := DiscoverPageIcons(io.LimitReader(resp.Body, maxPageBytes), finalURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse website: %v", err)
 	}
 
-	faviconURL = matches[1]
-	if !strings.HasPrefix(faviconURL, "http") {
-		// Handle relative URLs
-		if strings.HasPrefix(faviconURL, "//") {
-			faviconURL = "https:" + faviconURL
-		} else if strings.HasPrefix(faviconURL, "/") {
-			faviconURL = fmt.Sprintf("%s%s", parsedURL, faviconURL)
-		} else {
-			faviconURL = fmt.Sprintf("%s/%s", parsedURL, faviconURL)
-		}
+	candidates := icons.Candidates
+	if icons.ManifestURL != "" {
+		candidates = append(candidates, fetchManifestIcons(client, icons.ManifestURL)...)
 	}
+	candidates = append(candidates, DefaultIconCandidate(finalURL))
 
-	// Fetch the favicon
-	resp, err = client.Get(faviconURL)
+	return RankIconCandidates(candidates), nil
+}
+
+// fetchManifestIcons downloads a web app manifest and returns its icons, or none on failure
+func fetchManifestIcons(client *http.Client, manifestURL string) []IconCandidate {
+	resp, err := client.Get(manifestURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch favicon: %v", err)
+		return nil
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch favicon, status code: %d", resp.StatusCode)
+		return nil
 	}
 
-	return io.ReadAll(resp.Body)
+	if checkContentType(resp, manifestContentTypes) != nil {
+		return nil
+	}
+
+	data, err := readLimited(resp.Body, maxManifestBytes)
+	if err != nil {
+		return nil
+	}
+	return ParseManifestIcons(data, resp.Request.URL)
 }
 
-// GenerateSlug creates a URL-friendly slug from a project name
-func GenerateSlug(projectName string) string {
-	// Convert to lowercase
-	slug := strings.ToLower(projectName)
+// fetchIcon downloads an icon and checks that it really is an image, since many
+// sites answer missing files with an HTML page
+func fetchIcon(client *http.Client, iconURL string) ([]byte, error) {
+	resp, err := client.Get(iconURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch favicon: %v", err)
+	}
+	defer resp.Body.Close()
 
-	// Replace spaces with hyphens
-	slug = strings.ReplaceAll(slug, " ", "-")
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch favicon %s, status code: %d", iconURL, resp.StatusCode)
+	}
 
-	// Remove special characters
-	reg := regexp.MustCompile(`[^a-z0-9-]`)
-	slug = reg.ReplaceAllString(slug, "")
+	if err := checkContentType(resp, iconContentTypes); err != nil {
+		return nil, fmt.Errorf("favicon at %s is not usable: %v", iconURL, err)
+	}
 
-	// Remove consecutive hyphens
-	for strings.Contains(slug, "--") {
-		slug = strings.ReplaceAll(slug, "--", "-")
+	data, err := readLimited(resp.Body, maxFaviconBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read favicon: %v", err)
 	}
 
-	// Trim leading and trailing hyphens
-	slug = strings.Trim(slug, "-")
+	if _, err := SniffFaviconFormat(data); err != nil {
+		return nil, fmt.Errorf("favicon at %s is not usable: %v", iconURL, err)
+	}
 
-	return slug
+	return data, nil
 }
 
 // GetFaviconPath generates the proper file path for a project favicon
 func (fh *FaviconHandler) GetFaviconPath(projectName string) string {
-	slug := GenerateSlug(projectName)
-	logosDir := filepath.Join(fh.BaseDirectory, "data", "logos", slug)
-	return filepath.Join(logosDir, "favicon.png")
+	return filepath.Join(fh.BaseDirectory, logoRelDir(projectName), faviconFileName)
 }
 
-// SaveFavicon saves favicon data to the filesystem
-func (fh *FaviconHandler) SaveFavicon(projectName string, faviconData []byte) (string, error) {
+// FindFavicon returns the path of the stored logo for a project, preferring the PNG
+// favicon over an SVG logo, or an error satisfying os.IsNotExist if there is none
+func (fh *FaviconHandler) FindFavicon(projectName string) (string, error) {
+	logosDir, err := fh.logosDir(projectName)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range []string{faviconFileName, svgLogoFileName} {
+		path := filepath.Join(logosDir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+// SaveFavicon decodes favicon data of any supported format and saves it to the filesystem.
+// sourceURL is recorded in the logo manifest and may be empty.
+func (fh *FaviconHandler) SaveFavicon(projectName string, faviconData []byte, sourceURL string) (string, error) {
 	if len(faviconData) == 0 {
 		return "", errors.New("favicon data cannot be empty")
 	}
 
-	slug := GenerateSlug(projectName)
-	logosDir := filepath.Join(fh.BaseDirectory, "data", "logos", slug)
+	favicon, err := DecodeFavicon(faviconData)
+	if err != nil {
+		return "", err
+	}
+
+	return fh.SaveFaviconImage(projectName, favicon, sourceURL)
+}
+
+// SaveFaviconImage stores a decoded favicon as a PNG, or as logo.svg for vector images.
+// Raster logos are also stored in every size of logoSizes, and a logo.json manifest
+// describes the set.
+func (fh *FaviconHandler) SaveFaviconImage(projectName string, favicon *FaviconImage, sourceURL string) (string, error) {
+	logosDir, err := fh.logosDir(projectName)
+	if err != nil {
+		return "", err
+	}
 
 	// Create the logos directory if it doesn't exist
 	if err := os.MkdirAll(logosDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create directory: %v", err)
 	}
 
-	faviconPath := filepath.Join(logosDir, "favicon.png")
+	// Drop the previous logo set so no stale format or size is left behind
+	for _, name := range logoFileNames() {
+		if err := os.Remove(filepath.Join(logosDir, name)); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to remove previous logo: %v", err)
+		}
+	}
 
-	// Write the favicon file
-	if err := os.WriteFile(faviconPath, faviconData, 0644); err != nil {
-		return "", fmt.Errorf("failed to write favicon file: %v", err)
+	faviconPath, err := writeLogoAssets(logosDir, favicon, sourceURL)
+	if err != nil {
+		return "", err
 	}
 
 	// Return the relative path to the favicon
@@ -169,23 +239,26 @@ func (fh *FaviconHandler) SaveFavicon(projectName string, faviconData []byte) (s
 
 // RemoveFavicon deletes a project's favicon
 func (fh *FaviconHandler) RemoveFavicon(projectName string) error {
-	faviconPath := fh.GetFaviconPath(projectName)
-
-	if _, err := os.Stat(faviconPath); os.IsNotExist(err) {
-		return nil // File doesn't exist, so nothing to remove
+	logosDir, err := fh.logosDir(projectName)
+	if err != nil {
+		return err
 	}
 
-	if err := os.Remove(faviconPath); err != nil {
-		return fmt.Errorf("failed to remove favicon: %v", err)
+	for _, name := range logoFileNames() {
+		faviconPath := filepath.Join(logosDir, name)
+
+		if _, err := os.Stat(faviconPath); os.IsNotExist(err) {
+			continue // File doesn't exist, so nothing to remove
+		}
+
+		if err := os.Remove(faviconPath); err != nil {
+			return fmt.Errorf("failed to remove favicon: %v", err)
+		}
 	}
 
 	// Try to remove the directory if it's empty
-	slug := GenerateSlug(projectName)
-	logosDir := filepath.Join(fh.BaseDirectory, "data", "logos", slug)
-
 	// Check if directory is empty
-	entries, err := os.ReadDir(logosDir)
-	if err == nil && len(entries) == 0 {
+	if entries, err := os.ReadDir(logosDir); err == nil && len(entries) == 0 {
 		// Directory is empty, try to remove it
 		if err := os.Remove(logosDir); err != nil {
 			// Non-critical error, just log it
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // Register GIF decoder
+	_ "image/jpeg" // Register JPEG decoder
+	"image/png"
+	"net/http"
+	"strings"
+
+	_ "golang.org/x/image/bmp"  // Register BMP decoder
+	_ "golang.org/x/image/webp" // Register WebP decoder
+)
+
+// Favicon formats recognized by content sniffing
+const (
+	faviconFormatPNG  = "png"
+	faviconFormatICO  = "ico"
+	faviconFormatSVG  = "svg"
+	faviconFormatJPEG = "jpeg"
+	faviconFormatGIF  = "gif"
+	faviconFormatBMP  = "bmp"
+	faviconFormatWebP = "webp"
+)
+
+// maxFaviconDimension bounds the width and height of raster favicons, so a small
+// compressed file cannot expand into an enormous image when decoded
+const maxFaviconDimension = 4096
+
+// FaviconImage is a decoded favicon. Raster formats are decoded into Image,
+// SVG documents are kept as-is in SVG.
+type FaviconImage struct {
+	Format string
+	Image  image.Image
+	SVG    []byte
+}
+
+// IsSVG tells whether the favicon is a vector image that is stored without conversion
+func (fi *FaviconImage) IsSVG() bool {
+	return fi.Format == faviconFormatSVG
+}
+
+// ContentType returns the MIME type of the favicon as it is served and stored
+func (fi *FaviconImage) ContentType() string {
+	if fi.IsSVG() {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Encode returns the bytes to store: the SVG document or a re-encoded PNG
+func (fi *FaviconImage) Encode() ([]byte, error) {
+	if fi.IsSVG() {
+		return fi.SVG, nil
+	}
+
+	return encodePNG(fi.Image)
+}
+
+// SniffFaviconFormat detects the image format of data, or returns an error
+// describing what was received instead (e.g. an HTML error page)
+func SniffFaviconFormat(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", errors.New("favicon data cannot be empty")
+	}
+
+	if len(data) >= 4 && binary.LittleEndian.Uint16(data[0:2]) == 0 && binary.LittleEndian.Uint16(data[2:4]) == 1 {
+		return faviconFormatICO, nil
+	}
+
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/png":
+		return faviconFormatPNG, nil
+	case "image/jpeg":
+		return faviconFormatJPEG, nil
+	case "image/gif":
+		return faviconFormatGIF, nil
+	case "image/bmp":
+		return faviconFormatBMP, nil
+	case "image/webp":
+		return faviconFormatWebP, nil
+	case "image/x-icon", "image/vnd.microsoft.icon":
+		return faviconFormatICO, nil
+	}
+
+	if looksLikeSVG(data) {
+		return faviconFormatSVG, nil
+	}
+
+	return "", fmt.Errorf("payload is not an image (detected %s)", contentType)
+}
+
+// looksLikeSVG reports whether data is an XML document with an <svg> root element
+func looksLikeSVG(data []byte) bool {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	text := strings.ToLower(strings.TrimSpace(string(head)))
+	text = strings.TrimPrefix(text, "\ufeff")
+
+	if !strings.HasPrefix(text, "<?xml") && !strings.HasPrefix(text, "<svg") && !strings.HasPrefix(text, "<!--") && !strings.HasPrefix(text, "<!doctype svg") {
+		return false
+	}
+	return strings.Contains(text, "<svg")
+}
+
+// DecodeFavicon sniffs and decodes favicon data, rejecting anything that is not an image
+func DecodeFavicon(data []byte) (*FaviconImage, error) {
+	format, err := SniffFaviconFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case faviconFormatSVG:
+		return &FaviconImage{Format: format, SVG: data}, nil
+	case faviconFormatICO:
+		img, err := decodeICO(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode ICO: %v", err)
+		}
+		return &FaviconImage{Format: format, Image: img}, nil
+	}
+
+	if err := checkImageSize(data); err != nil {
+		return nil, fmt.Errorf("failed to decode %s image: %v", format, err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s image: %v", format, err)
+	}
+	return &FaviconImage{Format: format, Image: img}, nil
+}
+
+// checkImageSize reads only the header of an image and rejects dimensions above
+// maxFaviconDimension before any pixels are decoded
+func checkImageSize(data []byte) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if config.Width > maxFaviconDimension || config.Height > maxFaviconDimension {
+		return fmt.Errorf("image is %dx%d, larger than %dx%d", config.Width, config.Height, maxFaviconDimension, maxFaviconDimension)
+	}
+	return nil
+}
+
+// icoEntry is one image in an ICO directory
+type icoEntry struct {
+	width, height int
+	bitCount      int
+	size, offset  int
+}
+
+// decodeICO decodes the largest frame of an ICO file. Frames may be stored
+// as embedded PNGs or as headerless BMP (DIB) data with an AND mask.
+func decodeICO(data []byte) (image.Image, error) {
+	if len(data) < 6 {
+		return nil, errors.New("file too short")
+	}
+
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 {
+		return nil, errors.New("no images in icon")
+	}
+	if len(data) < 6+count*16 {
+		return nil, errors.New("truncated icon directory")
+	}
+
+	var best *icoEntry
+	for i := 0; i < count; i++ {
+		raw := data[6+i*16 : 6+(i+1)*16]
+		entry := icoEntry{
+			width:    int(raw[0]),
+			height:   int(raw[1]),
+			bitCount: int(binary.LittleEndian.Uint16(raw[6:8])),
+			size:     int(binary.LittleEndian.Uint32(raw[8:12])),
+			offset:   int(binary.LittleEndian.Uint32(raw[12:16])),
+		}
+		// A dimension of 0 means 256 pixels
+		if entry.width == 0 {
+			entry.width = 256
+		}
+		if entry.height == 0 {
+			entry.height = 256
+		}
+		if entry.offset < 0 || entry.size <= 0 || entry.offset+entry.size > len(data) {
+			continue
+		}
+
+		if best == nil ||
+			entry.width*entry.height > best.width*best.height ||
+			(entry.width*entry.height == best.width*best.height && entry.bitCount > best.bitCount) {
+			e := entry
+			best = &e
+		}
+	}
+
+	if best == nil {
+		return nil, errors.New("no valid images in icon")
+	}
+
+	frame := data[best.offset : best.offset+best.size]
+	if bytes.HasPrefix(frame, []byte("\x89PNG\r\n\x1a\n")) {
+		// The directory entry does not bound the size of an embedded PNG
+		if err := checkImageSize(frame); err != nil {
+			return nil, err
+		}
+		return png.Decode(bytes.NewReader(frame))
+	}
+	return decodeDIB(frame)
+}
+
+// decodeDIB decodes a BITMAPINFOHEADER bitmap as stored inside ICO files,
+// where the height covers both the colour bitmap and the 1-bit AND mask
+func decodeDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, errors.New("bitmap header too short")
+	}
+
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12]))) / 2
+	bitCount := int(binary.LittleEndian.Uint16(data[14:16]))
+	compression := binary.LittleEndian.Uint32(data[16:20])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:36]))
+
+	if width <= 0 || height <= 0 || width > 1024 || height > 1024 {
+		return nil, fmt.Errorf("unsupported bitmap size %dx%d", width, height)
+	}
+	if compression != 0 && compression != 3 {
+		return nil, fmt.Errorf("unsupported bitmap compression %d", compression)
+	}
+	if headerSize < 40 || headerSize > len(data) {
+		return nil, errors.New("invalid bitmap header size")
+	}
+
+	// Indexed bitmaps carry a BGRA palette after the header
+	var palette []color.NRGBA
+	pos := headerSize
+	if bitCount <= 8 {
+		if colorsUsed == 0 {
+			colorsUsed = 1 << bitCount
+		}
+		if colorsUsed > 256 {
+			return nil, fmt.Errorf("invalid bitmap palette size %d", colorsUsed)
+		}
+		if pos+colorsUsed*4 > len(data) {
+			return nil, errors.New("truncated bitmap palette")
+		}
+		for i := 0; i < colorsUsed; i++ {
+			p := data[pos+i*4:]
+			palette = append(palette, color.NRGBA{R: p[2], G: p[1], B: p[0], A: 255})
+		}
+		pos += colorsUsed * 4
+	}
+
+	stride := ((width*bitCount + 31) / 32) * 4
+	maskStride := ((width + 31) / 32) * 4
+	if pos+stride*height > len(data) {
+		return nil, errors.New("truncated bitmap data")
+	}
+	pixels := data[pos : pos+stride*height]
+	mask := data[pos+stride*height:]
+	hasMask := len(mask) >= maskStride*height
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+
+	for y := 0; y < height; y++ {
+		row := pixels[(height-1-y)*stride:]
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch bitCount {
+			case 32:
+				p := row[x*4:]
+				c = color.NRGBA{R: p[2], G: p[1], B: p[0], A: p[3]}
+				if p[3] != 0 {
+					hasAlpha = true
+				}
+			case 24:
+				p := row[x*3:]
+				c = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 255}
+			case 8, 4, 1:
+				bitPos := x * bitCount
+				index := int(row[bitPos/8]>>(8-bitCount-bitPos%8)) & (1<<bitCount - 1)
+				if index < len(palette) {
+					c = palette[index]
+				}
+			default:
+				return nil, fmt.Errorf("unsupported bitmap depth %d", bitCount)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// 32-bit bitmaps without a real alpha channel are opaque apart from the mask
+	if bitCount == 32 && !hasAlpha {
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 255
+		}
+	}
+
+	// Apply the AND mask unless the bitmap already has a real alpha channel
+	if hasMask && !hasAlpha {
+		for y := 0; y < height; y++ {
+			row := mask[(height-1-y)*maskStride:]
+			for x := 0; x < width; x++ {
+				if row[x/8]&(0x80>>(x%8)) != 0 {
+					img.SetNRGBA(x, y, color.NRGBA{})
+				}
+			}
+		}
+	}
+
+	return img, nil
+}
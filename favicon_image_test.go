@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// pngHeader returns the start of a PNG file declaring the given size, which is all
+// image.DecodeConfig reads
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], width)
+	binary.BigEndian.PutUint32(ihdr[4:8], height)
+	ihdr[8], ihdr[9] = 8, 6 // 8-bit RGBA
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return buf.Bytes()
+}
+
+func TestDecodeFaviconRejectsHugeImages(t *testing.T) {
+	_, err := DecodeFavicon(pngHeader(100000, 100000))
+	if err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("DecodeFavicon() error = %v, want a size error", err)
+	}
+
+	_, err = DecodeFavicon(pngHeader(maxFaviconDimension+1, 16))
+	if err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("DecodeFavicon() error = %v, want a size error", err)
+	}
+}
+
+func TestDecodeFaviconAcceptsSmallImages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 32, 32))); err != nil {
+		t.Fatal(err)
+	}
+
+	favicon, err := DecodeFavicon(buf.Bytes())
+	if err != nil {
+		t.Fatalf("DecodeFavicon() error = %v", err)
+	}
+	if favicon.Format != faviconFormatPNG || favicon.Image.Bounds().Dx() != 32 {
+		t.Errorf("DecodeFavicon() = %s %v, want a 32px PNG", favicon.Format, favicon.Image.Bounds())
+	}
+}
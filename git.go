@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// GitResult holds the outcome of a single git invocation
+type GitResult struct {
+	Command  []string `json:"command"`
+	Stdout   string   `json:"stdout"`
+	Stderr   string   `json:"stderr"`
+	ExitCode int      `json:"exitCode"`
+}
+
+// GitRequest describes one of the whitelisted git operations the extension can trigger
+type GitRequest struct {
+	Operation string `json:"operation"`
+	Message   string `json:"message,omitempty"`
+	Branch    string `json:"branch,omitempty"`
+}
+
+// Supported git operations
+const (
+	gitOpAdd    = "add"
+	gitOpCommit = "commit"
+	gitOpPull   = "pull"
+	gitOpPush   = "push"
+)
+
+var branchNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// validBranchName reports whether a client-given branch name is safe to pass to git.
+// Names starting with "-" could be read as options and are rejected.
+func validBranchName(branch string) bool {
+	return branchNamePattern.MatchString(branch) && !strings.Contains(branch, "..")
+}
+
+// runGit executes git with the given arguments inside the checkout and captures its output.
+// A non-zero exit status is reported through both the result and the returned error.
+func (s *Server) runGit(args ...string) (*GitResult, error) {
+	s.gitMutex.Lock()
+	defer s.gitMutex.Unlock()
+
+	var stdout, stderr bytes.Buffer
+	cmd := s.gitCommand(args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	result := &GitResult{
+		Command: append([]string{"git"}, args...),
+		Stdout:  stdout.String(),
+		Stderr:  stderr.String(),
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+			return result, fmt.Errorf("git %s exited with code %d", gitSubcommand(args), result.ExitCode)
+		}
+		result.ExitCode = -1
+		return result, fmt.Errorf("error running git %s: %v", gitSubcommand(args), err)
+	}
+
+	return result, nil
+}
+
+// gitSubcommand names the git command in args, skipping leading -c options
+func gitSubcommand(args []string) string {
+	for len(args) > 2 && args[0] == "-c" {
+		args = args[2:]
+	}
+	return args[0]
+}
+
+// currentBranch returns the name of the branch checked out in the checkout
+func (s *Server) currentBranch() (string, error) {
+	result, err := s.runGit("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("error getting current branch: %v", err)
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
+
+// gitArgs validates a request and translates it into the git arguments to run
+func (s *Server) gitArgs(req GitRequest) ([]string, error) {
+	switch req.Operation {
+	case gitOpAdd:
+		// Only what the tool wrote is staged; other local edits are left alone
+		paths := s.stageablePaths(s.session.TouchedPaths())
+		if len(paths) == 0 {
+			return nil, errors.New("no files changed by this tool to stage")
+		}
+		return append([]string{"add", "--all", "--"}, paths...), nil
+
+	case gitOpCommit:
+		message := strings.TrimSpace(req.Message)
+		if message == "" {
+			return nil, errors.New("commit message is required")
+		}
+		return []string{"commit", "-m", message}, nil
+
+	case gitOpPull, gitOpPush:
+		branch := req.Branch
+		if branch == "" {
+			current, err := s.currentBranch()
+			if err != nil {
+				return nil, err
+			}
+			branch = current
+		}
+		if !validBranchName(branch) {
+			return nil, fmt.Errorf("invalid branch name: %q", branch)
+		}
+		if req.Operation == gitOpPull {
+			return []string{"pull", "--rebase", s.config.OriginRemote, branch}, nil
+		}
+		return []string{"push", s.config.OriginRemote, branch}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported git operation: %q", req.Operation)
+}
+
+// runGitOperation runs a validated git request and writes the structured result
+func (s *Server) runGitOperation(w http.ResponseWriter, req GitRequest) {
+	args, err := s.gitArgs(req)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := s.runGit(args...)
+
+	// A pull can add, change or remove any project file
+	if err == nil && req.Operation == gitOpPull {
+		s.rebuildIndex()
+	}
+
+	// Committed paths no longer need to be tracked as touched
+	if err == nil && req.Operation == gitOpCommit {
+		if files, statusErr := s.gitStatus(); statusErr == nil {
+			s.pruneTouchedPaths(files)
+		}
+	}
+
+	// Staged files may change after any git operation
+	if stageErr := s.refreshStagedFiles(); stageErr != nil {
+		log.Printf("Warning: Failed to refresh staged files: %v", stageErr)
+	}
+
+	response := Response{
+		StagedFiles: s.session.StagedFiles(),
+		Git:         result,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		log.Printf("Git %s failed: %v\nStderr: %s", req.Operation, err, result.Stderr)
+		response.Error = err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		response.Message = fmt.Sprintf("git %s completed successfully", req.Operation)
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// runGitCommandHandler runs one of the whitelisted git operations named in the request body
+func (s *Server) runGitCommandHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	var req GitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %v", err))
+		return
+	}
+
+	s.runGitOperation(w, req)
+}
+
+// gitOperationHandler returns a handler bound to a single git operation
+func (s *Server) gitOperationHandler(operation string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			setCorsHeaders(w)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+			return
+		}
+
+		setCorsHeaders(w)
+
+		var req GitRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %v", err))
+				return
+			}
+		}
+		req.Operation = operation
+
+		s.runGitOperation(w, req)
+	}
+}
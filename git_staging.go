@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// FileStatus is one changed path of the checkout as reported by git status
+type FileStatus struct {
+	Path     string `json:"path"`
+	Index    string `json:"index"`    // Status in the index, e.g. "A", "M", "D" or " "
+	Worktree string `json:"worktree"` // Status in the working tree, "?" for untracked files
+	Staged   bool   `json:"staged"`
+	ToolMade bool   `json:"toolMade"`
+}
+
+// StatusResponse separates the changes made by this tool from other local edits
+type StatusResponse struct {
+	ToolChanges    []FileStatus `json:"toolChanges"`
+	ForeignChanges []FileStatus `json:"foreignChanges"`
+}
+
+// isTouchedPath reports whether path is, or lies below, one of the touched paths
+func isTouchedPath(path string, touched []string) bool {
+	for _, t := range touched {
+		if path == t || strings.HasPrefix(path, t+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// stageablePaths drops paths git add would reject: ones that neither exist in the
+// working tree nor are known to git
+func (s *Server) stageablePaths(paths []string) []string {
+	var stageable []string
+	for _, path := range paths {
+		absPath, err := s.checkoutPath(path)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(absPath); err == nil {
+			stageable = append(stageable, path)
+			continue
+		}
+		if result, err := s.runGit("ls-files", "--", path); err == nil && strings.TrimSpace(result.Stdout) != "" {
+			stageable = append(stageable, path)
+		}
+	}
+	return stageable
+}
+
+// gitStatus lists every changed path of the checkout, marking the ones the tool touched
+func (s *Server) gitStatus() ([]FileStatus, error) {
+	result, err := s.runGit("status", "--porcelain=v1", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, fmt.Errorf("error getting git status: %v", err)
+	}
+
+	touched := s.session.TouchedPaths()
+	entries := strings.Split(result.Stdout, "\x00")
+
+	var files []FileStatus
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+
+		file := FileStatus{
+			Path:     entry[3:],
+			Index:    entry[0:1],
+			Worktree: entry[1:2],
+		}
+		file.Staged = file.Index != " " && file.Index != "?"
+		file.ToolMade = isTouchedPath(file.Path, touched)
+		files = append(files, file)
+
+		// Renames and copies are followed by their source path
+		if file.Index == "R" || file.Index == "C" {
+			i++
+		}
+	}
+
+	return files, nil
+}
+
+// pruneTouchedPaths forgets touched paths that no longer have any changes. It is only
+// called after a commit: a path that merely looks clean, e.g. one the user unstaged and
+// reverted by hand, stays touched until the batch is committed.
+func (s *Server) pruneTouchedPaths(files []FileStatus) {
+	var kept []string
+	for _, touched := range s.session.TouchedPaths() {
+		for _, file := range files {
+			if isTouchedPath(file.Path, []string{touched}) {
+				kept = append(kept, touched)
+				break
+			}
+		}
+	}
+	if err := s.session.SetTouchedPaths(kept); err != nil {
+		log.Printf("Warning: Failed to update touched paths: %v", err)
+	}
+}
+
+// getStatusHandler reports the checkout's changes, split into tool-made and foreign ones
+func (s *Server) getStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	files, err := s.gitStatus()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := StatusResponse{
+		ToolChanges:    []FileStatus{},
+		ForeignChanges: []FileStatus{},
+	}
+	for _, file := range files {
+		if file.ToolMade {
+			response.ToolChanges = append(response.ToolChanges, file)
+		} else {
+			response.ForeignChanges = append(response.ForeignChanges, file)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// unstageFileHandler removes a file from the index, keeping its working tree changes
+func (s *Server) unstageFileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	path, err := checkoutRelPath(r.URL.Query().Get("file"))
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if result, err := s.runGit("reset", "-q", "--", path); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error unstaging file: %v\nOutput: %s", err, result.Stderr))
+		return
+	}
+
+	if err := s.refreshStagedFiles(); err != nil {
+		log.Printf("Warning: Failed to refresh staged files: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{
+		Message:     fmt.Sprintf("%s unstaged", path),
+		StagedFiles: s.session.StagedFiles(),
+	})
+}
+
+// discardFileHandler throws away the tool's changes to a file or directory: tracked
+// files are restored from HEAD and new ones are deleted. Paths the tool did not
+// touch are refused so local edits are never lost.
+func (s *Server) discardFileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	path, err := checkoutRelPath(r.URL.Query().Get("file"))
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !isTouchedPath(path, s.session.TouchedPaths()) {
+		writeErrorResponse(w, http.StatusForbidden, fmt.Sprintf("%s was not changed by this tool", path))
+		return
+	}
+
+	if err := s.discardPath(path); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error discarding file: %v", err))
+		return
+	}
+
+	if strings.HasPrefix(path, "data/projects/") {
+		s.rebuildIndex()
+	}
+	if err := s.refreshStagedFiles(); err != nil {
+		log.Printf("Warning: Failed to refresh staged files: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{
+		Message:     fmt.Sprintf("Changes to %s discarded", path),
+		StagedFiles: s.session.StagedFiles(),
+	})
+}
+
+// discardPath unstages a path, restores what HEAD has below it and removes the rest
+func (s *Server) discardPath(path string) error {
+	if result, err := s.runGit("reset", "-q", "--", path); err != nil {
+		return fmt.Errorf("%v\nOutput: %s", err, result.Stderr)
+	}
+
+	tracked, err := s.runGit("ls-tree", "-r", "--name-only", "HEAD", "--", path)
+	if err != nil {
+		return fmt.Errorf("%v\nOutput: %s", err, tracked.Stderr)
+	}
+	if strings.TrimSpace(tracked.Stdout) != "" {
+		if result, err := s.runGit("checkout", "HEAD", "--", path); err != nil {
+			return fmt.Errorf("%v\nOutput: %s", err, result.Stderr)
+		}
+	}
+
+	if result, err := s.runGit("clean", "-f", "-d", "-q", "--", path); err != nil {
+		return fmt.Errorf("%v\nOutput: %s", err, result.Stderr)
+	}
+
+	var kept []string
+	for _, touched := range s.session.TouchedPaths() {
+		if !isTouchedPath(touched, []string{path}) {
+			kept = append(kept, touched)
+		}
+	}
+	if err := s.session.SetTouchedPaths(kept); err != nil {
+		log.Printf("Warning: Failed to update touched paths: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsTouchedPath(t *testing.T) {
+	touched := []string{"data/projects/b/bebop.yaml", "data/logos/bebop"}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"data/projects/b/bebop.yaml", true},
+		{"data/logos/bebop", true},
+		{"data/logos/bebop/favicon-32.png", true},
+		{"data/logos/bebop-dex/favicon.png", false},
+		{"data/projects/b/bebop.yaml.orig", false},
+		{"data/logos", false},
+		{"README.md", false},
+	}
+
+	for _, tt := range tests {
+		if got := isTouchedPath(tt.path, touched); got != tt.want {
+			t.Errorf("isTouchedPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+// newStagingFixture returns a server on a checkout with committed files, where the
+// tool changed the project bebop and added its logo, and the user edited other files
+func newStagingFixture(t *testing.T) *Server {
+	t.Helper()
+
+	s := newTestServer(t, false)
+	checkout := s.config.DirectoryPath
+	writeTestFile(t, checkout, "README.md", "readme\n")
+	writeTestFile(t, checkout, "data/projects/a/alpha.yaml", "name: alpha\n")
+	writeTestFile(t, checkout, "data/projects/b/bebop.yaml", "name: bebop\n")
+	gitTest(t, checkout, "add", "-A")
+	gitTest(t, checkout, "commit", "-q", "-m", "initial")
+
+	// Tool changes
+	writeTestFile(t, checkout, "data/projects/b/bebop.yaml", "name: bebop\ndisplay_name: Bebop\n")
+	writeTestFile(t, checkout, "data/logos/bebop/favicon.png", "png")
+	if err := s.stageFiles("data/projects/b/bebop.yaml", "data/logos/bebop"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Foreign changes, one of them staged by hand
+	writeTestFile(t, checkout, "README.md", "edited readme\n")
+	writeTestFile(t, checkout, "data/projects/a/alpha.yaml", "name: alpha\ndisplay_name: Alpha\n")
+	writeTestFile(t, checkout, "notes.txt", "notes\n")
+	gitTest(t, checkout, "add", "README.md")
+
+	return s
+}
+
+func TestStageablePaths(t *testing.T) {
+	s := newStagingFixture(t)
+	checkout := s.config.DirectoryPath
+	if err := os.Remove(filepath.Join(checkout, "data/projects/a/alpha.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.stageablePaths([]string{
+		"data/projects/b/bebop.yaml", // Changed
+		"data/projects/a/alpha.yaml", // Deleted but tracked
+		"data/logos/bebop",           // New directory
+		"data/logos/gamma",           // Neither on disk nor in git
+		"../outside",                 // Outside the checkout
+	})
+	want := []string{"data/projects/b/bebop.yaml", "data/projects/a/alpha.yaml", "data/logos/bebop"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stageablePaths() = %q, want %q", got, want)
+	}
+}
+
+func TestGitStatusSplitsToolChanges(t *testing.T) {
+	s := newStagingFixture(t)
+
+	files, err := s.gitStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]FileStatus)
+	for _, file := range files {
+		got[file.Path] = file
+	}
+	want := map[string]FileStatus{
+		"README.md":                    {Path: "README.md", Index: "M", Worktree: " ", Staged: true},
+		"data/logos/bebop/favicon.png": {Path: "data/logos/bebop/favicon.png", Index: "A", Worktree: " ", Staged: true, ToolMade: true},
+		"data/projects/a/alpha.yaml":   {Path: "data/projects/a/alpha.yaml", Index: " ", Worktree: "M"},
+		"data/projects/b/bebop.yaml":   {Path: "data/projects/b/bebop.yaml", Index: "M", Worktree: " ", Staged: true, ToolMade: true},
+		"notes.txt":                    {Path: "notes.txt", Index: "?", Worktree: "?"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("gitStatus() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// Reading the status keeps touched paths, even ones that look clean
+	gitTest(t, s.config.DirectoryPath, "reset", "-q", "--", "data/logos/bebop")
+	os.RemoveAll(filepath.Join(s.config.DirectoryPath, "data/logos/bebop"))
+	recorder := httptest.NewRecorder()
+	s.getStatusHandler(recorder, httptest.NewRequest(http.MethodGet, "/getStatus", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d", recorder.Code)
+	}
+	if touched := s.session.TouchedPaths(); len(touched) != 2 {
+		t.Errorf("touched paths = %q, want both kept", touched)
+	}
+}
+
+func TestPruneTouchedPaths(t *testing.T) {
+	s := newStagingFixture(t)
+	if err := s.session.TouchPaths("data/projects/c/gamma.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := s.gitStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.pruneTouchedPaths(files)
+
+	want := []string{"data/logos/bebop", "data/projects/b/bebop.yaml"}
+	if got := s.session.TouchedPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("touched paths = %q, want %q", got, want)
+	}
+}
+
+func TestDiscardFileHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		status int
+		want   map[string]string // Files that must have this content afterwards, "" for deleted
+	}{
+		{
+			"tracked file is restored",
+			"data/projects/b/bebop.yaml", http.StatusOK,
+			map[string]string{"data/projects/b/bebop.yaml": "name: bebop\n", "data/logos/bebop/favicon.png": "png"},
+		},
+		{
+			"new directory is removed",
+			"data/logos/bebop", http.StatusOK,
+			map[string]string{"data/logos/bebop/favicon.png": "", "data/projects/b/bebop.yaml": "name: bebop\ndisplay_name: Bebop\n"},
+		},
+		{"foreign change", "data/projects/a/alpha.yaml", http.StatusForbidden, nil},
+		{"foreign staged change", "README.md", http.StatusForbidden, nil},
+		{"untracked foreign file", "notes.txt", http.StatusForbidden, nil},
+		{"parent of a touched path", "data/logos", http.StatusForbidden, nil},
+		{"outside the checkout", "../README.md", http.StatusBadRequest, nil},
+	}
+
+	foreign := map[string]string{
+		"README.md":                  "edited readme\n",
+		"data/projects/a/alpha.yaml": "name: alpha\ndisplay_name: Alpha\n",
+		"notes.txt":                  "notes\n",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStagingFixture(t)
+			checkout := s.config.DirectoryPath
+
+			recorder := httptest.NewRecorder()
+			s.discardFileHandler(recorder, httptest.NewRequest(http.MethodPost, "/discardFile?file="+tt.file, nil))
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d (%s), want %d", recorder.Code, recorder.Body.String(), tt.status)
+			}
+
+			want := make(map[string]string)
+			for path, content := range foreign {
+				want[path] = content
+			}
+			for path, content := range tt.want {
+				want[path] = content
+			}
+			for path, content := range want {
+				data, err := os.ReadFile(filepath.Join(checkout, path))
+				if content == "" {
+					if !os.IsNotExist(err) {
+						t.Errorf("%s still exists", path)
+					}
+					continue
+				}
+				if err != nil || string(data) != content {
+					t.Errorf("%s = %q, %v; want %q", path, data, err, content)
+				}
+			}
+
+			// The foreign staged change stays staged
+			if staged := gitTest(t, checkout, "diff", "--cached", "--name-only", "--", "README.md"); staged != "README.md" {
+				t.Errorf("README.md is no longer staged")
+			}
+			if tt.status == http.StatusOK && isTouchedPath(tt.file, s.session.TouchedPaths()) {
+				t.Errorf("%s is still touched after discarding it", tt.file)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGitSubcommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"status"}, "status"},
+		{[]string{"commit", "-m", "msg"}, "commit"},
+		{[]string{"-c", "user.name=Test", "commit", "-m", "msg"}, "commit"},
+		{[]string{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit"}, "commit"},
+		{[]string{"-c", "user.name=Test"}, "-c"},
+	}
+
+	for _, tt := range tests {
+		if got := gitSubcommand(tt.args); got != tt.want {
+			t.Errorf("gitSubcommand(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRunGitErrorNamesSubcommand(t *testing.T) {
+	s := newTestServer(t, false)
+
+	result, err := s.runGit("-c", "user.name=Test", "frobnicate")
+	if err == nil || !strings.HasPrefix(err.Error(), "git frobnicate exited with code") {
+		t.Errorf("runGit() error = %v, want it to name the subcommand", err)
+	}
+	if result == nil || result.ExitCode == 0 || result.Command[1] != "-c" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+// newGitFixture returns a server on a checkout with one commit on main and a file
+// the tool changed
+func newGitFixture(t *testing.T) *Server {
+	t.Helper()
+
+	s := newTestServer(t, false)
+	checkout := s.config.DirectoryPath
+	gitTest(t, checkout, "config", "user.name", "Test")
+	gitTest(t, checkout, "config", "user.email", "test@example.com")
+	writeTestFile(t, checkout, "data/projects/a/alpha.yaml", "name: alpha\n")
+	writeTestFile(t, checkout, "README.md", "readme\n")
+	gitTest(t, checkout, "add", "-A")
+	gitTest(t, checkout, "commit", "-q", "-m", "initial")
+	gitTest(t, checkout, "branch", "-M", "main")
+
+	writeTestFile(t, checkout, "data/projects/a/alpha.yaml", "name: alpha\ndisplay_name: Alpha\n")
+	if err := s.session.TouchPaths("data/projects/a/alpha.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, checkout, "README.md", "edited readme\n")
+	return s
+}
+
+func TestGitArgs(t *testing.T) {
+	s := newGitFixture(t)
+
+	tests := []struct {
+		req  GitRequest
+		want []string
+		err  string
+	}{
+		{GitRequest{Operation: gitOpAdd}, []string{"add", "--all", "--", "data/projects/a/alpha.yaml"}, ""},
+		{GitRequest{Operation: gitOpCommit, Message: "  feat: add alpha \n"}, []string{"commit", "-m", "feat: add alpha"}, ""},
+		{GitRequest{Operation: gitOpCommit, Message: " "}, nil, "commit message is required"},
+		{GitRequest{Operation: gitOpPull}, []string{"pull", "--rebase", "origin", "main"}, ""},
+		{GitRequest{Operation: gitOpPush, Branch: "feature/alpha"}, []string{"push", "origin", "feature/alpha"}, ""},
+		{GitRequest{Operation: gitOpPush, Branch: "--force"}, nil, "invalid branch name"},
+		{GitRequest{Operation: gitOpPull, Branch: "main..evil"}, nil, "invalid branch name"},
+		{GitRequest{Operation: "reset"}, nil, "unsupported git operation"},
+		{GitRequest{Operation: "-c"}, nil, "unsupported git operation"},
+		{GitRequest{}, nil, "unsupported git operation"},
+	}
+
+	for _, tt := range tests {
+		args, err := s.gitArgs(tt.req)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("gitArgs(%+v) error = %v, want %q", tt.req, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(args, tt.want) {
+			t.Errorf("gitArgs(%+v) = %q, %v; want %q", tt.req, args, err, tt.want)
+		}
+	}
+
+	// Nothing to stage once the tool has no changes left
+	if err := s.session.SetTouchedPaths(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.gitArgs(GitRequest{Operation: gitOpAdd}); err == nil {
+		t.Error("gitArgs(add) succeeded without touched paths")
+	}
+}
+
+func TestRunGitCommandHandler(t *testing.T) {
+	s := newGitFixture(t)
+	checkout := s.config.DirectoryPath
+	head := gitTest(t, checkout, "rev-parse", "HEAD")
+
+	runGitCommand := func(body string) (int, Response) {
+		recorder := httptest.NewRecorder()
+		s.runGitCommandHandler(recorder, httptest.NewRequest(http.MethodPost, "/runGitCommand", bytes.NewBufferString(body)))
+		var response Response
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response
+	}
+
+	for _, body := range []string{
+		`{"operation": "reset"}`,
+		`{"operation": "checkout", "branch": "main"}`,
+		`{"operation": "push", "branch": "-f"}`,
+		`{"operation": `,
+	} {
+		if status, response := runGitCommand(body); status != http.StatusBadRequest || response.Git != nil {
+			t.Errorf("runGitCommand(%s) status = %d, git = %+v; want 400 without running git", body, status, response.Git)
+		}
+	}
+	if current := gitTest(t, checkout, "rev-parse", "HEAD"); current != head {
+		t.Fatal("a rejected request changed HEAD")
+	}
+
+	if status, response := runGitCommand(`{"operation": "add"}`); status != http.StatusOK {
+		t.Fatalf("add status = %d (%s)", status, response.Error)
+	}
+	status, response := runGitCommand(`{"operation": "commit", "message": "feat: add alpha"}`)
+	if status != http.StatusOK || response.Git == nil || response.Git.Command[1] != "commit" {
+		t.Fatalf("commit status = %d (%s), git = %+v", status, response.Error, response.Git)
+	}
+
+	// Only the tool's change was committed
+	if committed := gitTest(t, checkout, "show", "--name-only", "--format=", "HEAD"); committed != "data/projects/a/alpha.yaml" {
+		t.Errorf("committed files = %q", committed)
+	}
+	if unstaged := gitTest(t, checkout, "diff", "--name-only"); unstaged != "README.md" {
+		t.Errorf("unstaged files = %q, want README.md left alone", unstaged)
+	}
+
+	// A failing operation reports the git result
+	status, response = runGitCommand(`{"operation": "push", "branch": "main"}`)
+	if status != http.StatusInternalServerError || response.Git == nil || response.Git.ExitCode == 0 {
+		t.Errorf("push without a remote status = %d, git = %+v; want 500 with the result", status, response.Git)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.runGitCommandHandler(recorder, httptest.NewRequest(http.MethodGet, "/runGitCommand", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", recorder.Code)
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// OrgMetadata is what a code host knows about the organization behind a project
+type OrgMetadata struct {
+	Login           string        `json:"login"`
+	Name            string        `json:"name,omitempty"`
+	Blog            string        `json:"blog,omitempty"`
+	TwitterUsername string        `json:"twitterUsername,omitempty"`
+	URL             string        `json:"url"`
+	Repositories    []RepoSummary `json:"repositories"`
+}
+
+// RepoSummary is one public repository of an organization
+type RepoSummary struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	Fork        bool   `json:"fork,omitempty"`
+	Archived    bool   `json:"archived,omitempty"`
+}
+
+// MetadataProvider looks up organization metadata by GitHub login
+type MetadataProvider interface {
+	Organization(login string) (*OrgMetadata, error)
+}
+
+// errOrgNotFound is returned by providers when the login does not exist
+var errOrgNotFound = errors.New("organization not found")
+
+// githubLoginPattern matches valid GitHub user and organization names
+var githubLoginPattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,38})$`)
+
+// GitHubLogin extracts the organization login from a github.com URL
+func GitHubLogin(rawURL string) (string, error) {
+	host, segment := urlIdentity(rawURL)
+	if host != "github.com" || segment == "" {
+		return "", fmt.Errorf("%q is not a github.com/<org> URL", rawURL)
+	}
+	if !githubLoginPattern.MatchString(segment) {
+		return "", fmt.Errorf("%q is not a valid GitHub login", segment)
+	}
+	return segment, nil
+}
+
+// GitHubProvider reads organization metadata from the GitHub REST API
+type GitHubProvider struct {
+	BaseURL string // API root, e.g. https://api.github.com
+	Token   string // Optional; raises the rate limit
+	Client  *http.Client
+}
+
+// Pagination limits for repository listings
+const (
+	githubReposPerPage = 100
+	githubMaxRepoPages = 5
+)
+
+// NewGitHubProvider creates a provider for the GitHub REST API at baseURL
+func NewGitHubProvider(baseURL, token string) *GitHubProvider {
+	return &GitHubProvider{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Token:   token,
+		Client:  &http.Client{Timeout: fetchTimeout},
+	}
+}
+
+// Organization fetches an organization, falling back to a user account of that
+// name, along with its public repositories
+func (gp *GitHubProvider) Organization(login string) (*OrgMetadata, error) {
+	var account struct {
+		Login           string `json:"login"`
+		Name            string `json:"name"`
+		Blog            string `json:"blog"`
+		TwitterUsername string `json:"twitter_username"`
+		HTMLURL         string `json:"html_url"`
+		Type            string `json:"type"`
+	}
+
+	kind := "orgs"
+	err := gp.get(fmt.Sprintf("/orgs/%s", url.PathEscape(login)), &account)
+	if errors.Is(err, errOrgNotFound) {
+		kind = "users"
+		err = gp.get(fmt.Sprintf("/users/%s", url.PathEscape(login)), &account)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &OrgMetadata{
+		Login:           account.Login,
+		Name:            account.Name,
+		Blog:            account.Blog,
+		TwitterUsername: account.TwitterUsername,
+		URL:             account.HTMLURL,
+		Repositories:    []RepoSummary{},
+	}
+
+	for page := 1; page <= githubMaxRepoPages; page++ {
+		var repos []struct {
+			Name        string `json:"name"`
+			HTMLURL     string `json:"html_url"`
+			Description string `json:"description"`
+			Fork        bool   `json:"fork"`
+			Archived    bool   `json:"archived"`
+		}
+		path := fmt.Sprintf("/%s/%s/repos?per_page=%d&page=%d&sort=full_name", kind, url.PathEscape(login), githubReposPerPage, page)
+		if err := gp.get(path, &repos); err != nil {
+			return nil, err
+		}
+
+		for _, repo := range repos {
+			metadata.Repositories = append(metadata.Repositories, RepoSummary{
+				Name:        repo.Name,
+				URL:         repo.HTMLURL,
+				Description: repo.Description,
+				Fork:        repo.Fork,
+				Archived:    repo.Archived,
+			})
+		}
+		if len(repos) < githubReposPerPage {
+			break
+		}
+	}
+
+	return metadata, nil
+}
+
+// GitHubAPIError is a non-success response from the GitHub REST API
+type GitHubAPIError struct {
+	StatusCode int
+	Path       string
+	Message    string
+}
+
+func (e *GitHubAPIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("GitHub API returned status %d for %s: %s", e.StatusCode, e.Path, e.Message)
+	}
+	return fmt.Sprintf("GitHub API returned status %d for %s", e.StatusCode, e.Path)
+}
+
+// get decodes the JSON response of a GitHub API request
+func (gp *GitHubProvider) get(path string, target interface{}) error {
+	err := gp.do(http.MethodGet, path, nil, target)
+
+	var apiErr *GitHubAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return errOrgNotFound
+	}
+	return err
+}
+
+// do sends a GitHub API request with an optional JSON payload and decodes the
+// JSON response into target
+func (gp *GitHubProvider) do(method, path string, payload, target interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, gp.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if gp.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+gp.Token)
+	}
+
+	resp, err := gp.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("GitHub API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := readLimited(resp.Body, maxPageBytes)
+	if err != nil {
+		return fmt.Errorf("error reading GitHub API response: %v", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &GitHubAPIError{StatusCode: resp.StatusCode, Path: path, Message: githubErrorMessage(data)}
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("error decoding GitHub API response: %v", err)
+	}
+	return nil
+}
+
+// githubErrorMessage extracts the message and validation errors of an error response
+func githubErrorMessage(data []byte) string {
+	var body struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(data, &body) != nil {
+		return ""
+	}
+
+	messages := []string{}
+	if body.Message != "" {
+		messages = append(messages, body.Message)
+	}
+	for _, e := range body.Errors {
+		if e.Message != "" {
+			messages = append(messages, e.Message)
+		}
+	}
+	return strings.Join(messages, ": ")
+}
+
+// FixtureProvider serves organization metadata from <login>.json files in a
+// directory, for working offline and for tests
+type FixtureProvider struct {
+	Directory string
+}
+
+// NewFixtureProvider creates a provider reading OrgMetadata JSON files from dir
+func NewFixtureProvider(dir string) *FixtureProvider {
+	return &FixtureProvider{Directory: dir}
+}
+
+// Organization loads the fixture for login
+func (fp *FixtureProvider) Organization(login string) (*OrgMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(fp.Directory, login+".json"))
+	if os.IsNotExist(err) {
+		return nil, errOrgNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading fixture: %v", err)
+	}
+
+	var metadata OrgMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("error parsing fixture %s.json: %v", login, err)
+	}
+	if metadata.Repositories == nil {
+		metadata.Repositories = []RepoSummary{}
+	}
+	return &metadata, nil
+}
+
+// newMetadataProvider picks the fixture provider when fixtures are configured
+func newMetadataProvider(cfg Config) MetadataProvider {
+	if cfg.GitHubFixtures != "" {
+		return NewFixtureProvider(cfg.GitHubFixtures)
+	}
+	return NewGitHubProvider(cfg.GitHubAPIURL, cfg.GitHubToken)
+}
+
+// FieldProposal is a suggested value for a project field that is currently empty
+type FieldProposal struct {
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// ProposeEnrichment suggests values for the project's missing fields from its
+// organization metadata and returns a copy of the project with them applied
+func ProposeEnrichment(project *Project, org *OrgMetadata) ([]FieldProposal, *Project) {
+	enriched := *project
+	proposals := []FieldProposal{}
+	source := "GitHub organization " + org.Login
+
+	if enriched.DisplayName == "" && org.Name != "" {
+		enriched.DisplayName = org.Name
+		proposals = append(proposals, FieldProposal{Field: "display_name", Value: org.Name, Source: source})
+	}
+
+	if len(enriched.Websites) == 0 && org.Blog != "" {
+		blog := org.Blog
+		if !strings.Contains(blog, "://") {
+			blog = "https://" + blog
+		}
+		enriched.Websites = []URL{{Url: blog}}
+		proposals = append(proposals, FieldProposal{Field: "websites[0].url", Value: blog, Source: source})
+	}
+
+	if org.TwitterUsername != "" && (enriched.Social == nil || len(enriched.Social.Twitter) == 0) {
+		social := Social{}
+		if enriched.Social != nil {
+			social = *enriched.Social
+		}
+		twitter := "https://x.com/" + strings.TrimPrefix(org.TwitterUsername, "@")
+		social.Twitter = []URL{{Url: twitter}}
+		enriched.Social = &social
+		proposals = append(proposals, FieldProposal{Field: "social.twitter[0].url", Value: twitter, Source: source})
+	}
+
+	return proposals, &enriched
+}
+
+// EnrichmentResponse reports what the organization behind a project says about it
+type EnrichmentResponse struct {
+	Organization *OrgMetadata    `json:"organization"`
+	Proposals    []FieldProposal `json:"proposals"`
+	Project      *Project        `json:"project"`
+}
+
+// enrichProjectHandler looks up the GitHub organization of the posted project and
+// proposes values for its missing fields. Nothing is written.
+func (s *Server) enrichProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	var project Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %v", err))
+		return
+	}
+
+	if len(project.Github) == 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "Project has no github URL to enrich from")
+		return
+	}
+
+	login, err := GitHubLogin(project.Github[0].Url)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	org, err := s.github.Organization(login)
+	if errors.Is(err, errOrgNotFound) {
+		writeErrorResponse(w, http.StatusNotFound, fmt.Sprintf("GitHub organization %q not found", login))
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadGateway, fmt.Sprintf("Error fetching GitHub organization: %v", err))
+		return
+	}
+
+	proposals, enriched := ProposeEnrichment(&project, org)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EnrichmentResponse{
+		Organization: org,
+		Proposals:    proposals,
+		Project:      enriched,
+	})
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitHubLogin(t *testing.T) {
+	tests := []struct {
+		url, login string
+		ok         bool
+	}{
+		{"https://github.com/ethereum", "ethereum", true},
+		{"https://github.com/Ethereum/go-ethereum", "ethereum", true},
+		{"github.com/opensource-observer/", "opensource-observer", true},
+		{"http://www.github.com/bebop-dex?tab=repositories", "bebop-dex", true},
+		{"https://github.com/", "", false},
+		{"https://gitlab.com/ethereum", "", false},
+		{"https://github.com/-leading-hyphen", "", false},
+		{"https://github.com/under_score", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		login, err := GitHubLogin(tt.url)
+		if (err == nil) != tt.ok || login != tt.login {
+			t.Errorf("GitHubLogin(%q) = %q, %v; want %q, ok=%v", tt.url, login, err, tt.login, tt.ok)
+		}
+	}
+}
+
+func TestProposeEnrichmentFillsOnlyEmptyFields(t *testing.T) {
+	org := &OrgMetadata{Login: "bebop-dex", Name: "Bebop", Blog: "bebop.xyz", TwitterUsername: "@bebop_dex"}
+
+	t.Run("empty project", func(t *testing.T) {
+		project := &Project{Name: "bebop"}
+		proposals, enriched := ProposeEnrichment(project, org)
+
+		if len(proposals) != 3 {
+			t.Fatalf("got %d proposals, want 3: %+v", len(proposals), proposals)
+		}
+		if enriched.DisplayName != "Bebop" {
+			t.Errorf("DisplayName = %q", enriched.DisplayName)
+		}
+		if len(enriched.Websites) != 1 || enriched.Websites[0].Url != "https://bebop.xyz" {
+			t.Errorf("Websites = %+v", enriched.Websites)
+		}
+		if enriched.Social == nil || enriched.Social.Twitter[0].Url != "https://x.com/bebop_dex" {
+			t.Errorf("Social = %+v", enriched.Social)
+		}
+		if project.DisplayName != "" || project.Social != nil {
+			t.Errorf("ProposeEnrichment modified its input: %+v", project)
+		}
+	})
+
+	t.Run("filled project", func(t *testing.T) {
+		project := &Project{
+			Name:        "bebop",
+			DisplayName: "Bebop DEX",
+			Websites:    []URL{{Url: "https://bebop.exchange"}},
+			Social:      &Social{Twitter: []URL{{Url: "https://x.com/other"}}, Discord: []URL{{Url: "https://discord.gg/bebop"}}},
+		}
+		proposals, enriched := ProposeEnrichment(project, org)
+
+		if len(proposals) != 0 {
+			t.Errorf("got proposals for filled fields: %+v", proposals)
+		}
+		if enriched.DisplayName != "Bebop DEX" || enriched.Websites[0].Url != "https://bebop.exchange" || enriched.Social.Twitter[0].Url != "https://x.com/other" {
+			t.Errorf("filled fields were overwritten: %+v", enriched)
+		}
+	})
+
+	t.Run("partly filled social", func(t *testing.T) {
+		project := &Project{Name: "bebop", DisplayName: "Bebop", Social: &Social{Discord: []URL{{Url: "https://discord.gg/bebop"}}}}
+		_, enriched := ProposeEnrichment(project, org)
+
+		if len(enriched.Social.Discord) != 1 || len(enriched.Social.Twitter) != 1 {
+			t.Errorf("Social = %+v, want Discord kept and Twitter added", enriched.Social)
+		}
+		if len(project.Social.Twitter) != 0 {
+			t.Error("ProposeEnrichment modified the input's social links")
+		}
+	})
+}
+
+// newGitHubStub serves a minimal GitHub API: an organization with 101 repositories,
+// a user account, and an error response
+func newGitHubStub(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/acme", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"login": "acme", "name": "Acme", "blog": "https://acme.dev",
+			"twitter_username": "acme", "html_url": "https://github.com/acme",
+		})
+	})
+	mux.HandleFunc("GET /orgs/acme/repos", func(w http.ResponseWriter, r *http.Request) {
+		count := githubReposPerPage
+		if r.URL.Query().Get("page") == "2" {
+			count = 1
+		}
+		repos := []map[string]interface{}{}
+		for i := 0; i < count; i++ {
+			name := fmt.Sprintf("repo-%s-%d", r.URL.Query().Get("page"), i)
+			repos = append(repos, map[string]interface{}{"name": name, "html_url": "https://github.com/acme/" + name, "fork": i == 0})
+		}
+		json.NewEncoder(w).Encode(repos)
+	})
+	mux.HandleFunc("GET /users/jane", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"login": "jane", "html_url": "https://github.com/jane"})
+	})
+	mux.HandleFunc("GET /users/jane/repos", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]string{{"name": "dotfiles"}})
+	})
+	mux.HandleFunc("GET /orgs/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGitHubProviderOrganization(t *testing.T) {
+	server := newGitHubStub(t)
+	provider := NewGitHubProvider(server.URL+"/", "secret")
+
+	org, err := provider.Organization("acme")
+	if err != nil {
+		t.Fatalf("Organization(acme) error = %v", err)
+	}
+	if org.Name != "Acme" || org.Blog != "https://acme.dev" || org.TwitterUsername != "acme" || org.URL != "https://github.com/acme" {
+		t.Errorf("Organization(acme) = %+v", org)
+	}
+	if len(org.Repositories) != githubReposPerPage+1 {
+		t.Errorf("got %d repositories, want %d across two pages", len(org.Repositories), githubReposPerPage+1)
+	}
+	if !org.Repositories[0].Fork {
+		t.Errorf("first repository = %+v, want a fork", org.Repositories[0])
+	}
+}
+
+func TestGitHubProviderFallsBackToUsers(t *testing.T) {
+	server := newGitHubStub(t)
+	provider := NewGitHubProvider(server.URL, "secret")
+
+	user, err := provider.Organization("jane")
+	if err != nil {
+		t.Fatalf("Organization(jane) error = %v", err)
+	}
+	if user.Login != "jane" || len(user.Repositories) != 1 {
+		t.Errorf("Organization(jane) = %+v", user)
+	}
+}
+
+func TestGitHubProviderErrors(t *testing.T) {
+	server := newGitHubStub(t)
+	provider := NewGitHubProvider(server.URL, "secret")
+
+	if _, err := provider.Organization("nobody"); !errors.Is(err, errOrgNotFound) {
+		t.Errorf("Organization(nobody) error = %v, want errOrgNotFound", err)
+	}
+
+	_, err := provider.Organization("broken")
+	var apiErr *GitHubAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || apiErr.Message != "API rate limit exceeded" {
+		t.Errorf("Organization(broken) error = %v, want a 403 GitHubAPIError", err)
+	}
+}
+
+func TestFixtureProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "acme.json"), []byte(`{"login":"acme","name":"Acme"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider := NewFixtureProvider(dir)
+
+	org, err := provider.Organization("acme")
+	if err != nil || org.Name != "Acme" || org.Repositories == nil {
+		t.Errorf("Organization(acme) = %+v, %v", org, err)
+	}
+	if _, err := provider.Organization("nobody"); !errors.Is(err, errOrgNotFound) {
+		t.Errorf("Organization(nobody) error = %v, want errOrgNotFound", err)
+	}
+}
@@ -2,4 +2,9 @@ module yaml_project_creator
 
 go 1.22
 
-require gopkg.in/yaml.v2 v2.4.0
+require (
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
+	golang.org/x/image v0.18.0
+	golang.org/x/net v0.25.0
+	gopkg.in/yaml.v2 v2.4.0
+)
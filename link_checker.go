@@ -0,0 +1,419 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for the link checker
+const (
+	defaultLinkWorkers      = 8
+	defaultLinkHostInterval = time.Second
+	linkCacheTTL            = time.Hour
+	linkUserAgent           = "oss-project-adder-linkcheck/1.0"
+	maxLinkCheckBodyBytes   = 64 << 10
+)
+
+// LinkResult is the outcome of checking one URL
+type LinkResult struct {
+	URL       string    `json:"url"`
+	Status    int       `json:"status,omitempty"`
+	OK        bool      `json:"ok"`
+	FinalURL  string    `json:"finalUrl,omitempty"` // Set when the URL redirects somewhere else
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Moved tells whether the link redirects to a different canonical URL, e.g. a renamed GitHub org
+func (lr LinkResult) Moved() bool {
+	return lr.FinalURL != ""
+}
+
+// LinkIssue is a broken or moved link of a project
+type LinkIssue struct {
+	Field string `json:"field"`
+	LinkResult
+}
+
+// ProjectLinkReport lists the problem links of one project
+type ProjectLinkReport struct {
+	Name   string      `json:"name"`
+	Path   string      `json:"path"`
+	Broken []LinkIssue `json:"broken"`
+	Moved  []LinkIssue `json:"moved"`
+}
+
+// LinkReport is the result of checking every link in the directory
+type LinkReport struct {
+	StartedAt  time.Time           `json:"startedAt"`
+	FinishedAt time.Time           `json:"finishedAt"`
+	Checked    int                 `json:"checked"` // Unique URLs checked
+	Broken     int                 `json:"broken"`
+	Moved      int                 `json:"moved"`
+	Projects   []ProjectLinkReport `json:"projects"` // Only projects with broken or moved links
+}
+
+// LinkCheckOptions tunes the concurrency and politeness of the link checker
+type LinkCheckOptions struct {
+	Workers      int
+	HostInterval time.Duration
+}
+
+// LinkChecker checks project URLs, caching results between runs
+type LinkChecker struct {
+	client  *http.Client
+	options LinkCheckOptions
+	limiter *hostLimiter
+
+	mutex sync.Mutex
+	cache map[string]LinkResult
+}
+
+// NewLinkChecker creates a link checker that sends requests with client
+func NewLinkChecker(client *http.Client, options LinkCheckOptions) *LinkChecker {
+	if options.Workers <= 0 {
+		options.Workers = defaultLinkWorkers
+	}
+	return &LinkChecker{
+		client:  client,
+		options: options,
+		limiter: newHostLimiter(options.HostInterval),
+		cache:   make(map[string]LinkResult),
+	}
+}
+
+// Check checks a single URL, answering from the cache when the last result is recent
+func (lc *LinkChecker) Check(rawURL string) LinkResult {
+	lc.mutex.Lock()
+	cached, ok := lc.cache[rawURL]
+	lc.mutex.Unlock()
+	if ok && time.Since(cached.CheckedAt) < linkCacheTTL {
+		return cached
+	}
+
+	lc.limiter.Wait(rawURL)
+	result := lc.check(rawURL)
+
+	lc.mutex.Lock()
+	lc.cache[rawURL] = result
+	lc.mutex.Unlock()
+	return result
+}
+
+// check sends a HEAD request and falls back to GET for servers that reject HEAD
+func (lc *LinkChecker) check(rawURL string) LinkResult {
+	result := LinkResult{URL: rawURL, CheckedAt: time.Now()}
+
+	target := rawURL
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+
+	resp, err := lc.request(http.MethodHead, target)
+	if err != nil || resp.StatusCode >= 400 {
+		if err == nil {
+			resp.Body.Close()
+		}
+		resp, err = lc.request(http.MethodGet, target)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxLinkCheckBodyBytes))
+
+	result.Status = resp.StatusCode
+	result.OK = resp.StatusCode < 400 || resp.StatusCode == http.StatusTooManyRequests
+	if finalURL := resp.Request.URL.String(); linkMoved(target, finalURL) {
+		result.FinalURL = finalURL
+	}
+	return result
+}
+
+// linkMoved tells whether a redirect from one URL to another points at something else:
+// a different host, or a different handle on a shared host such as a renamed GitHub
+// organization. Scheme upgrades and redirects within a site, e.g. to a locale, are not moves.
+func linkMoved(from, to string) bool {
+	fromHost, fromHandle := urlIdentity(from)
+	toHost, toHandle := urlIdentity(to)
+	if fromHost != toHost {
+		return true
+	}
+	return sharedHosts[fromHost] && fromHandle != toHandle
+}
+
+func (lc *LinkChecker) request(method, target string) (*http.Response, error) {
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", linkUserAgent)
+	return lc.client.Do(req)
+}
+
+// CheckProjects checks every URL of the given projects with a bounded worker pool
+// and builds a report of the broken and moved links per project
+func (lc *LinkChecker) CheckProjects(projects []*IndexedProject) *LinkReport {
+	report := &LinkReport{StartedAt: time.Now(), Projects: []ProjectLinkReport{}}
+
+	var urls []string
+	seen := make(map[string]bool)
+	for _, entry := range projects {
+		for _, field := range projectURLFields(entry.Project) {
+			for _, u := range field.urls {
+				if u.Url != "" && !seen[u.Url] {
+					seen[u.Url] = true
+					urls = append(urls, u.Url)
+				}
+			}
+		}
+	}
+
+	results := make(map[string]LinkResult, len(urls))
+	var resultsMutex sync.Mutex
+	queue := make(chan string)
+	var wg sync.WaitGroup
+
+	for i := 0; i < lc.options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range queue {
+				result := lc.Check(u)
+				resultsMutex.Lock()
+				results[u] = result
+				resultsMutex.Unlock()
+			}
+		}()
+	}
+	for _, u := range urls {
+		queue <- u
+	}
+	close(queue)
+	wg.Wait()
+
+	report.Checked = len(urls)
+	for _, entry := range projects {
+		projectReport := ProjectLinkReport{Name: entry.Project.Name, Path: entry.Path, Broken: []LinkIssue{}, Moved: []LinkIssue{}}
+		for _, field := range projectURLFields(entry.Project) {
+			for i, u := range field.urls {
+				result, ok := results[u.Url]
+				if !ok {
+					continue
+				}
+				issue := LinkIssue{Field: fmt.Sprintf("%s[%d].url", field.name, i), LinkResult: result}
+				switch {
+				case !result.OK:
+					projectReport.Broken = append(projectReport.Broken, issue)
+				case result.Moved():
+					projectReport.Moved = append(projectReport.Moved, issue)
+				}
+			}
+		}
+
+		if len(projectReport.Broken)+len(projectReport.Moved) > 0 {
+			report.Broken += len(projectReport.Broken)
+			report.Moved += len(projectReport.Moved)
+			report.Projects = append(report.Projects, projectReport)
+		}
+	}
+
+	sort.Slice(report.Projects, func(i, j int) bool {
+		return report.Projects[i].Name < report.Projects[j].Name
+	})
+	report.FinishedAt = time.Now()
+	return report
+}
+
+// Project returns the report of a single project, or nil if its links are fine
+func (lr *LinkReport) Project(name string) *ProjectLinkReport {
+	for i := range lr.Projects {
+		if lr.Projects[i].Name == name {
+			return &lr.Projects[i]
+		}
+	}
+	return nil
+}
+
+// Markdown renders the report as a Markdown document
+func (lr *LinkReport) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Link check report\n\n")
+	fmt.Fprintf(&b, "Checked %d links on %s: %d broken, %d moved.\n", lr.Checked, lr.FinishedAt.Format(time.RFC1123), lr.Broken, lr.Moved)
+
+	for _, project := range lr.Projects {
+		fmt.Fprintf(&b, "\n## %s\n\n`%s`\n\n", project.Name, project.Path)
+		for _, issue := range project.Broken {
+			fmt.Fprintf(&b, "- **broken** `%s` %s (%s)\n", issue.Field, issue.URL, linkProblem(issue.LinkResult))
+		}
+		for _, issue := range project.Moved {
+			fmt.Fprintf(&b, "- **moved** `%s` %s → %s\n", issue.Field, issue.URL, issue.FinalURL)
+		}
+	}
+
+	return b.String()
+}
+
+// linkProblem describes why a link is broken
+func linkProblem(result LinkResult) string {
+	if result.Error != "" {
+		return result.Error
+	}
+	return fmt.Sprintf("HTTP %d", result.Status)
+}
+
+// LinkCheckStatus is the state of the server's link check
+type LinkCheckStatus struct {
+	Running bool        `json:"running"`
+	Report  *LinkReport `json:"report,omitempty"` // Last finished report
+}
+
+// startLinkCheck checks every project's links in the background unless a check is running
+func (s *Server) startLinkCheck() error {
+	s.linkMutex.Lock()
+	defer s.linkMutex.Unlock()
+
+	if s.linkCheckRunning {
+		return fmt.Errorf("a link check is already running")
+	}
+	s.linkCheckRunning = true
+
+	go func() {
+		report := s.links.CheckProjects(s.index.All())
+		log.Printf("Link check finished: %d links, %d broken, %d moved", report.Checked, report.Broken, report.Moved)
+
+		s.linkMutex.Lock()
+		s.linkReport = report
+		s.linkCheckRunning = false
+		s.linkMutex.Unlock()
+	}()
+
+	return nil
+}
+
+// checkLinksHandler starts a link check of the whole directory
+func (s *Server) checkLinksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	if err := s.startLinkCheck(); err != nil {
+		writeErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(Response{Message: "Link check started"})
+}
+
+// getLinkReportHandler returns the last link check report as JSON, or as Markdown
+// with format=markdown. The project parameter limits it to one project's broken links.
+func (s *Server) getLinkReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	s.linkMutex.Lock()
+	status := LinkCheckStatus{Running: s.linkCheckRunning, Report: s.linkReport}
+	s.linkMutex.Unlock()
+
+	if name := r.URL.Query().Get("project"); name != "" {
+		if status.Report == nil {
+			writeErrorResponse(w, http.StatusNotFound, "No link check has finished yet")
+			return
+		}
+		project := status.Report.Project(name)
+		if project == nil {
+			project = &ProjectLinkReport{Name: name, Path: projectRelPath(name), Broken: []LinkIssue{}, Moved: []LinkIssue{}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(project)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "markdown" {
+		if status.Report == nil {
+			writeErrorResponse(w, http.StatusNotFound, "No link check has finished yet")
+			return
+		}
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		io.WriteString(w, status.Report.Markdown())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// runCheckLinksCommand implements the check-links subcommand, which checks every
+// link in the directory and prints the report
+func runCheckLinksCommand(args []string) {
+	fs := flag.NewFlagSet("check-links", flag.ContinueOnError)
+	workers := fs.Int("workers", defaultLinkWorkers, "number of concurrent link checks")
+	hostInterval := fs.Duration("host-interval", defaultLinkHostInterval, "minimum delay between requests to the same host")
+	format := fs.String("format", "markdown", "report format: markdown or json")
+	output := fs.String("output", "", "file to write the report to (default stdout)")
+
+	cfg, err := LoadConfigFlags(fs, args)
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if *format != "markdown" && *format != "json" {
+		log.Fatalf("-format must be markdown or json")
+	}
+
+	index := NewProjectIndex(cfg.DirectoryPath)
+	if err := index.Rebuild(); err != nil {
+		log.Fatalf("Error indexing projects: %v", err)
+	}
+
+	checker := NewLinkChecker(NewFetchClient(FetchOptions{}), LinkCheckOptions{
+		Workers:      *workers,
+		HostInterval: *hostInterval,
+	})
+	report := checker.CheckProjects(index.All())
+
+	var data []byte
+	if *format == "json" {
+		data, err = json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding report: %v", err)
+		}
+		data = append(data, '\n')
+	} else {
+		data = []byte(report.Markdown())
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+	log.Printf("Wrote link report to %s (%d broken, %d moved)", *output, report.Broken, report.Moved)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// handlerTransport answers every request with an http.Handler, so the checker can be
+// pointed at any host without network access
+type handlerTransport struct {
+	handler http.Handler
+
+	mutex   sync.Mutex
+	methods map[string][]string // Methods used per URL
+}
+
+func (ht *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ht.mutex.Lock()
+	ht.methods[req.URL.String()] = append(ht.methods[req.URL.String()], req.Method)
+	ht.mutex.Unlock()
+
+	if req.URL.Host == "unreachable.test" {
+		return nil, errors.New("connection refused")
+	}
+
+	// Redirected requests leave Host empty, but the mux routes by host
+	served := req.Clone(req.Context())
+	served.Host = req.URL.Host
+
+	recorder := httptest.NewRecorder()
+	ht.handler.ServeHTTP(recorder, served)
+	resp := recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+func newTestLinkChecker() (*LinkChecker, *handlerTransport) {
+	redirect := func(target string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, target, http.StatusMovedPermanently) }
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("no-head.test/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("gone.test/", http.NotFound)
+	mux.HandleFunc("busy.test/", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTooManyRequests) })
+	mux.HandleFunc("github.com/old-org", redirect("https://github.com/new-org"))
+	mux.HandleFunc("github.com/new-org", ok)
+	mux.HandleFunc("github.com/new-org/", ok)
+	mux.HandleFunc("github.com/Same-Org", redirect("https://github.com/same-org/"))
+	mux.HandleFunc("github.com/same-org/", ok)
+	mux.HandleFunc("plain.test/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Scheme == "http" {
+			http.Redirect(w, r, "https://plain.test/", http.StatusMovedPermanently)
+		}
+	})
+	mux.HandleFunc("locale.test/{$}", redirect("/en/"))
+	mux.HandleFunc("locale.test/en/", ok)
+	mux.HandleFunc("www.redirects.test/", redirect("https://redirects.test/"))
+	mux.HandleFunc("redirects.test/", ok)
+	mux.HandleFunc("old.test/", redirect("https://new.test/"))
+	mux.HandleFunc("new.test/", ok)
+
+	transport := &handlerTransport{handler: mux, methods: make(map[string][]string)}
+	checker := NewLinkChecker(&http.Client{Transport: transport}, LinkCheckOptions{Workers: 2})
+	return checker, transport
+}
+
+func TestLinkCheckerCheck(t *testing.T) {
+	tests := []struct {
+		url      string
+		ok       bool
+		finalURL string
+	}{
+		{"https://no-head.test/", true, ""},
+		{"https://gone.test/", false, ""},
+		{"https://busy.test/", true, ""},
+		{"https://unreachable.test/", false, ""},
+		{"https://github.com/old-org", true, "https://github.com/new-org"},
+		{"https://github.com/Same-Org", true, ""},
+		{"http://plain.test/", true, ""},
+		{"https://locale.test/", true, ""},
+		{"https://www.redirects.test/", true, ""},
+		{"https://old.test/", true, "https://new.test/"},
+	}
+
+	checker, _ := newTestLinkChecker()
+	for _, tt := range tests {
+		result := checker.Check(tt.url)
+		if result.OK != tt.ok || result.FinalURL != tt.finalURL {
+			t.Errorf("Check(%q) = ok %v, final %q (%+v); want ok %v, final %q", tt.url, result.OK, result.FinalURL, result, tt.ok, tt.finalURL)
+		}
+	}
+}
+
+func TestLinkCheckerFallsBackToGet(t *testing.T) {
+	checker, transport := newTestLinkChecker()
+
+	result := checker.Check("https://no-head.test/")
+	if !result.OK || result.Status != http.StatusOK {
+		t.Fatalf("Check() = %+v, want 200", result)
+	}
+	methods := transport.methods["https://no-head.test/"]
+	if len(methods) != 2 || methods[0] != http.MethodHead || methods[1] != http.MethodGet {
+		t.Errorf("methods = %v, want HEAD then GET", methods)
+	}
+
+	// Results are cached
+	checker.Check("https://no-head.test/")
+	if len(transport.methods["https://no-head.test/"]) != 2 {
+		t.Errorf("cached URL was requested again: %v", transport.methods["https://no-head.test/"])
+	}
+}
+
+func TestLinkCheckerCheckProjects(t *testing.T) {
+	checker, _ := newTestLinkChecker()
+
+	projects := []*IndexedProject{
+		{Path: "data/projects/a/alpha.yaml", Project: &Project{
+			Name:     "alpha",
+			Websites: []URL{{Url: "https://gone.test/"}, {Url: "http://plain.test/"}},
+			Github:   []URL{{Url: "https://github.com/old-org"}},
+		}},
+		{Path: "data/projects/b/beta.yaml", Project: &Project{
+			Name:     "beta",
+			Websites: []URL{{Url: "https://locale.test/"}},
+			Github:   []URL{{Url: "https://github.com/new-org"}},
+		}},
+		{Path: "data/projects/c/gamma.yaml", Project: &Project{
+			Name:   "gamma",
+			Social: &Social{Discord: []URL{{Url: "https://unreachable.test/"}}},
+		}},
+	}
+
+	report := checker.CheckProjects(projects)
+	if report.Checked != 6 || report.Broken != 2 || report.Moved != 1 {
+		t.Fatalf("report counts = checked %d, broken %d, moved %d; want 6, 2, 1", report.Checked, report.Broken, report.Moved)
+	}
+	if len(report.Projects) != 2 || report.Projects[0].Name != "alpha" || report.Projects[1].Name != "gamma" {
+		t.Fatalf("report projects = %+v, want alpha and gamma", report.Projects)
+	}
+
+	alpha := report.Project("alpha")
+	if len(alpha.Broken) != 1 || alpha.Broken[0].Field != "websites[0].url" {
+		t.Errorf("alpha broken = %+v", alpha.Broken)
+	}
+	if len(alpha.Moved) != 1 || alpha.Moved[0].Field != "github[0].url" || alpha.Moved[0].FinalURL != "https://github.com/new-org" {
+		t.Errorf("alpha moved = %+v", alpha.Moved)
+	}
+	if gamma := report.Project("gamma"); gamma.Broken[0].Error == "" {
+		t.Errorf("gamma broken = %+v, want a request error", gamma.Broken)
+	}
+	if report.Project("beta") != nil {
+		t.Error("beta has no problem links but is in the report")
+	}
+}
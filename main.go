@@ -11,79 +11,182 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
-
-	"gopkg.in/yaml.v2"
 )
 
-type Project struct {
-	Version     int     `json:"version" yaml:"version"`
-	Name        string  `json:"name" yaml:"name"`
-	DisplayName string  `json:"displayName" yaml:"display_name"`
-	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
-	Websites    []URL   `json:"websites,omitempty" yaml:"websites,omitempty"`
-	Github      []URL   `json:"github,omitempty" yaml:"github,omitempty"`
-	Social      *Social `json:"social,omitempty" yaml:"social,omitempty"`
+type Response struct {
+	Message     string     `json:"message"`
+	Error       string     `json:"error,omitempty"`
+	LatestFile  string     `json:"latestFile,omitempty"`
+	StagedFiles []string   `json:"stagedFiles,omitempty"`
+	FaviconPath string     `json:"faviconPath,omitempty"`
+	Git         *GitResult `json:"git,omitempty"`
+
+	ValidationErrors []ValidationError    `json:"validationErrors,omitempty"`
+	Duplicates       []DuplicateCandidate `json:"duplicates,omitempty"`
+	URLChanges       []URLChange          `json:"urlChanges,omitempty"`
 }
 
-type URL struct {
-	Url string `json:"url" yaml:"url"`
-}
+// Server holds the configuration and session state shared by all handlers
+type Server struct {
+	config    Config
+	favicons  *FaviconHandler
+	validator *ProjectValidator
+	index     *ProjectIndex
+	github    MetadataProvider
+	pulls     PullRequestClient
+
+	session *SessionStore
 
-type Social struct {
-	Twitter  []URL `json:"twitter,omitempty" yaml:"twitter,omitempty"`
-	Telegram []URL `json:"telegram,omitempty" yaml:"telegram,omitempty"`
-	Mirror   []URL `json:"mirror,omitempty" yaml:"mirror,omitempty"`
-	Discord  []URL `json:"discord,omitempty" yaml:"discord,omitempty"`
+	gitMutex sync.Mutex // Serializes git invocations in the checkout
+
+	backfillMutex sync.Mutex
+	backfill      *BackfillJob // Current or last favicon backfill job
+
+	links            *LinkChecker
+	linkMutex        sync.Mutex
+	linkCheckRunning bool
+	linkReport       *LinkReport // Last finished link check
 }
 
-type Response struct {
-	Message     string   `json:"message"`
-	Error       string   `json:"error,omitempty"`
-	LatestFile  string   `json:"latestFile,omitempty"`
-	StagedFiles []string `json:"stagedFiles,omitempty"`
-	FaviconPath string   `json:"faviconPath,omitempty"`
+// NewServer creates a Server operating on the checkout described by cfg
+func NewServer(cfg Config) (*Server, error) {
+	session, err := OpenSessionStore(cfg.StateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		config:    cfg,
+		favicons:  NewFaviconHandler(cfg.DirectoryPath),
+		validator: &ProjectValidator{},
+		index:     NewProjectIndex(cfg.DirectoryPath),
+		github:    newMetadataProvider(cfg),
+		pulls:     newPullRequestClient(cfg),
+		session:   session,
+		links: NewLinkChecker(NewFetchClient(FetchOptions{}), LinkCheckOptions{
+			HostInterval: defaultLinkHostInterval,
+		}),
+	}, nil
 }
 
-var (
-	latestFile     string
-	stagedFiles    []string
-	addedFiles     []string
-	mutex          sync.Mutex
-	faviconHandler *FaviconHandler
-)
+// loadValidator (re)loads the project schema from the checkout's schema directory
+func (s *Server) loadValidator() {
+	schemaDir := s.config.SchemaDir
+	if !filepath.IsAbs(schemaDir) {
+		schemaDir = filepath.Join(s.config.DirectoryPath, schemaDir)
+	}
+
+	validator, err := NewProjectValidator(schemaDir)
+	if err != nil {
+		log.Printf("Warning: JSON schema validation disabled: %v", err)
+	}
+	s.validator = validator
+}
 
 func main() {
-	if err := pullFromUpstream(); err != nil {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backfill-favicons":
+			runBackfillCommand(os.Args[2:])
+			return
+		case "check-links":
+			runCheckLinksCommand(os.Args[2:])
+			return
+		case "import-projects":
+			runImportCommand(os.Args[2:])
+			return
+		}
+	}
+
+	cfg, err := LoadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	s, err := NewServer(cfg)
+	if err != nil {
+		log.Fatalf("Error initializing server: %v", err)
+	}
+
+	if err := s.pullFromUpstream(); err != nil {
 		log.Printf("Warning: Failed to pull from upstream: %v", err)
 	}
 
-	// Initialize the favicon handler
-	faviconHandler = NewFaviconHandler("/Users/ahoura/documents/dev-projects/oss-directory")
+	// Load the schema after pulling so it matches the upstream data
+	s.loadValidator()
+	s.rebuildIndex()
+
+	log.Printf("Using oss-directory at %s", cfg.DirectoryPath)
+	log.Printf("Storing session state in %s", cfg.StateDir)
+	log.Printf("Server started on %s", cfg.ListenAddr)
+	log.Fatal(http.ListenAndServe(cfg.ListenAddr, s.routes()))
+}
 
-	http.HandleFunc("/createProject", createProjectHandler)
-	http.HandleFunc("/getLatestFile", getLatestFileHandler)
-	http.HandleFunc("/getCurrentBranch", getCurrentBranchHandler)
-	http.HandleFunc("/changeBranch", changeBranchHandler)
-	http.HandleFunc("/getAddedFiles", getAddedFilesHandler)
-	http.HandleFunc("/getFileContent", getFileContentHandler)
-	http.HandleFunc("/getStagedFiles", getStagedFilesHandler)
-	http.HandleFunc("/resetFiles", resetFilesHandler)
+// routes registers every API endpoint on a new ServeMux
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/createProject", s.createProjectHandler)
+	mux.HandleFunc("/previewProject", s.previewProjectHandler)
+	mux.HandleFunc("/updateProject", s.updateProjectHandler)
+	mux.HandleFunc("/listProjects", s.listProjectsHandler)
+	mux.HandleFunc("/searchProjects", s.searchProjectsHandler)
+	mux.HandleFunc("/checkDuplicates", s.checkDuplicatesHandler)
+	mux.HandleFunc("/importProjects", s.importProjectsHandler)
+	mux.HandleFunc("/draftProject", s.draftProjectHandler)
+	mux.HandleFunc("/enrichProject", s.enrichProjectHandler)
+	mux.HandleFunc("/checkLinks", s.checkLinksHandler)
+	mux.HandleFunc("/getLinkReport", s.getLinkReportHandler)
+	mux.HandleFunc("/listCollections", s.listCollectionsHandler)
+	mux.HandleFunc("/createCollection", s.createCollectionHandler)
+	mux.HandleFunc("/addToCollection", s.collectionProjectHandler(true))
+	mux.HandleFunc("/removeFromCollection", s.collectionProjectHandler(false))
+	mux.HandleFunc("/getLatestFile", s.getLatestFileHandler)
+	mux.HandleFunc("/getCurrentBranch", s.getCurrentBranchHandler)
+	mux.HandleFunc("/changeBranch", s.changeBranchHandler)
+	mux.HandleFunc("/getAddedFiles", s.getAddedFilesHandler)
+	mux.HandleFunc("/renameProject", s.renameProjectHandler)
+	mux.HandleFunc("/getFileContent", s.getFileContentHandler)
+	mux.HandleFunc("/getStagedFiles", s.getStagedFilesHandler)
+	mux.HandleFunc("/resetFiles", s.resetFilesHandler)
+	mux.HandleFunc("/getStatus", s.getStatusHandler)
+	mux.HandleFunc("/unstageFile", s.unstageFileHandler)
+	mux.HandleFunc("/discardFile", s.discardFileHandler)
+	mux.HandleFunc("/getHistory", s.getHistoryHandler)
+	mux.HandleFunc("/getHistoryDays", s.getHistoryDaysHandler)
 
 	// Register new favicon API endpoints
-	http.HandleFunc("/fetchFavicon", fetchFaviconHandler)
-	http.HandleFunc("/saveFavicon", saveFaviconHandler)
-	http.HandleFunc("/removeFavicon", removeFaviconHandler)
-	http.HandleFunc("/getFavicon", getFaviconHandler)
+	mux.HandleFunc("/fetchFavicon", s.fetchFaviconHandler)
+	mux.HandleFunc("/saveFavicon", s.saveFaviconHandler)
+	mux.HandleFunc("/removeFavicon", s.removeFaviconHandler)
+	mux.HandleFunc("/getFavicon", s.getFaviconHandler)
+	mux.HandleFunc("/backfillFavicons", s.backfillFaviconsHandler)
+	mux.HandleFunc("/getBackfillStatus", s.backfillStatusHandler)
+
+	// Whitelisted git operations used by the extension's git buttons
+	mux.HandleFunc("/runGitCommand", s.runGitCommandHandler)
+	mux.HandleFunc("/gitAdd", s.gitOperationHandler(gitOpAdd))
+	mux.HandleFunc("/gitCommit", s.gitOperationHandler(gitOpCommit))
+	mux.HandleFunc("/gitPull", s.gitOperationHandler(gitOpPull))
+	mux.HandleFunc("/gitPush", s.gitOperationHandler(gitOpPush))
+	mux.HandleFunc("/commitSession", s.commitSessionHandler)
+	mux.HandleFunc("/createPullRequest", s.createPullRequestHandler)
 
 	// Test endpoint for favicon functionality
-	http.HandleFunc("/testFavicon", testFaviconHandler)
+	mux.HandleFunc("/testFavicon", s.testFaviconHandler)
 
-	log.Println("Server started on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	return mux
+}
+
+// gitCommand prepares a git command that runs inside the oss-directory checkout
+func (s *Server) gitCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = s.config.DirectoryPath
+	return cmd
 }
 
 // Fetch favicon from a URL
-func fetchFaviconHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) fetchFaviconHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		setCorsHeaders(w)
 		w.WriteHeader(http.StatusOK)
@@ -103,18 +206,37 @@ func fetchFaviconHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	faviconData, err := faviconHandler.FetchFavicon(url)
+	opts, err := s.fetchOptions(r)
+	if err != nil {
+		writeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	faviconData, sourceURL, err := s.favicons.FetchFavicon(url, opts)
 	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error fetching favicon: %v", err))
 		return
 	}
 
-	w.Header().Set("Content-Type", "image/png")
-	w.Write(faviconData)
+	favicon, err := DecodeFavicon(faviconData)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadGateway, fmt.Sprintf("Error decoding favicon: %v", err))
+		return
+	}
+
+	data, err := favicon.Encode()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error encoding favicon: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", favicon.ContentType())
+	w.Header().Set("X-Favicon-Source", sourceURL)
+	w.Write(data)
 }
 
 // Save favicon for a project
-func saveFaviconHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) saveFaviconHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		setCorsHeaders(w)
 		w.WriteHeader(http.StatusOK)
@@ -129,8 +251,8 @@ func saveFaviconHandler(w http.ResponseWriter, r *http.Request) {
 	setCorsHeaders(w)
 
 	projectName := r.URL.Query().Get("projectName")
-	if projectName == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "projectName parameter is required")
+	if GenerateSlug(projectName) == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "A projectName parameter with letters or digits is required")
 		return
 	}
 
@@ -146,17 +268,26 @@ func saveFaviconHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	faviconPath, err := faviconHandler.SaveFavicon(projectName, faviconData)
+	favicon, err := DecodeFavicon(faviconData)
+	if err != nil {
+		writeErrorResponse(w, http.StatusUnsupportedMediaType, fmt.Sprintf("Invalid favicon: %v", err))
+		return
+	}
+
+	// The extension passes the URL it fetched the favicon from, if known
+	faviconPath, err := s.favicons.SaveFaviconImage(projectName, favicon, r.URL.Query().Get("sourceUrl"))
 	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error saving favicon: %v", err))
 		return
 	}
 
 	// Add favicon directory to git
-	if err := stageChanges(); err != nil {
+	if err := s.stageFiles(logoRelDir(projectName)); err != nil {
 		log.Printf("Warning: Failed to stage favicon changes: %v", err)
 	}
 
+	s.recordAction(faviconPath, actionFaviconSaved)
+
 	response := Response{
 		Message:     "Favicon saved successfully",
 		FaviconPath: faviconPath,
@@ -167,7 +298,7 @@ func saveFaviconHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // Remove favicon for a project
-func removeFaviconHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) removeFaviconHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		setCorsHeaders(w)
 		w.WriteHeader(http.StatusOK)
@@ -182,22 +313,26 @@ func removeFaviconHandler(w http.ResponseWriter, r *http.Request) {
 	setCorsHeaders(w)
 
 	projectName := r.URL.Query().Get("projectName")
-	if projectName == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "projectName parameter is required")
+	if GenerateSlug(projectName) == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "A projectName parameter with letters or digits is required")
 		return
 	}
 
-	err := faviconHandler.RemoveFavicon(projectName)
+	err := s.favicons.RemoveFavicon(projectName)
 	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error removing favicon: %v", err))
 		return
 	}
 
 	// Stage the changes (deleted files)
-	if err := stageChanges(); err != nil {
+	if err := s.stageFiles(logoRelDir(projectName)); err != nil {
 		log.Printf("Warning: Failed to stage favicon deletion: %v", err)
 	}
 
+	if relPath, err := filepath.Rel(s.config.DirectoryPath, s.favicons.GetFaviconPath(projectName)); err == nil {
+		s.recordAction(relPath, actionFaviconRemoved)
+	}
+
 	response := Response{
 		Message: "Favicon removed successfully",
 	}
@@ -207,7 +342,7 @@ func removeFaviconHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get favicon for a project
-func getFaviconHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) getFaviconHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		setCorsHeaders(w)
 		w.WriteHeader(http.StatusOK)
@@ -222,15 +357,37 @@ func getFaviconHandler(w http.ResponseWriter, r *http.Request) {
 	setCorsHeaders(w)
 
 	projectName := r.URL.Query().Get("projectName")
-	if projectName == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "projectName parameter is required")
+	if GenerateSlug(projectName) == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "A projectName parameter with letters or digits is required")
 		return
 	}
 
-	faviconPath := faviconHandler.GetFaviconPath(projectName)
+	// An optional size selects one of the generated square PNGs
+	if sizeParam := r.URL.Query().Get("size"); sizeParam != "" {
+		size, err := ParseLogoSize(sizeParam)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		data, contentType, err := s.favicons.FindLogoAsset(projectName, size)
+		if os.IsNotExist(err) {
+			writeErrorResponse(w, http.StatusNotFound, "Favicon not found for this project")
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error reading favicon file: %v", err))
+			return
+		}
+
+		setLogoHeaders(w, contentType)
+		w.Write(data)
+		return
+	}
 
 	// Check if the favicon exists
-	if _, err := os.Stat(faviconPath); os.IsNotExist(err) {
+	faviconPath, err := s.favicons.FindFavicon(projectName)
+	if err != nil {
 		writeErrorResponse(w, http.StatusNotFound, "Favicon not found for this project")
 		return
 	}
@@ -242,12 +399,25 @@ func getFaviconHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "image/png")
+	contentType := "image/png"
+	if filepath.Ext(faviconPath) == ".svg" {
+		contentType = "image/svg+xml"
+	}
+
+	setLogoHeaders(w, contentType)
 	w.Write(faviconData)
 }
 
+// setLogoHeaders sets the headers of a stored logo response, which keep scripts in
+// fetched SVGs from running when a logo is opened directly
+func setLogoHeaders(w http.ResponseWriter, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+}
+
 // Modified createProjectHandler to automatically handle favicon if website URL is provided
-func createProjectHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) createProjectHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		setCorsHeaders(w)
 		w.WriteHeader(http.StatusOK)
@@ -267,51 +437,77 @@ func createProjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project.Version = 7
+	opts, err := s.fetchOptions(r)
+	if err != nil {
+		writeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
 
-	data, err := yaml.Marshal(&project)
+	data, urlChanges, problems, err := s.prepareProject(&project)
+	if len(problems) > 0 {
+		writeValidationErrorResponse(w, problems)
+		return
+	}
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error marshalling YAML: %v", err))
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	gitDir := "/Users/ahoura/documents/dev-projects/oss-directory"
-	firstChar := strings.ToLower(string(project.Name[0]))
-	dirPath := filepath.Join(gitDir, "data/projects", firstChar)
+	filePath, err := s.projectPath(project.Name)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error creating directory: %v", err))
 		return
 	}
 
-	filePath := filepath.Join(dirPath, fmt.Sprintf("%s.yaml", project.Name))
 	if _, err := os.Stat(filePath); err == nil {
 		writeErrorResponse(w, http.StatusConflict, fmt.Sprintf("File %s already exists", filePath))
 		return
 	}
 
+	// Unless the user already reviewed the candidates, refuse to create a likely duplicate
+	if r.URL.Query().Get("force") != "true" {
+		if duplicates := s.index.FindDuplicates(&project); len(duplicates) > 0 {
+			log.Printf("Found %d possible duplicate(s) of %s", len(duplicates), project.Name)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(Response{
+				Error:      "Possible duplicate projects found; resend with force=true to create anyway",
+				Duplicates: duplicates,
+			})
+			return
+		}
+	}
+
 	if err := os.WriteFile(filePath, data, 0644); err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error writing file: %v", err))
 		return
 	}
+	s.refreshIndex(project.Name)
 
 	// Try to fetch and save favicon if website URL is provided
 	var faviconPath string
 	if len(project.Websites) > 0 && project.Websites[0].Url != "" {
 		websiteUrl := project.Websites[0].Url
-		faviconData, err := faviconHandler.FetchFavicon(websiteUrl)
+		faviconData, sourceURL, err := s.favicons.FetchFavicon(websiteUrl, opts)
 		if err == nil && len(faviconData) > 0 {
-			faviconPath, _ = faviconHandler.SaveFavicon(project.Name, faviconData)
+			faviconPath, _ = s.favicons.SaveFavicon(project.Name, faviconData, sourceURL)
 		}
 	}
 
-	mutex.Lock()
-	latestFile = fmt.Sprintf("%s.yaml", project.Name)
-	addedFiles = append(addedFiles, latestFile)
-	mutex.Unlock()
+	latestFile := fmt.Sprintf("%s.yaml", project.Name)
+	s.recordFile(latestFile, actionCreated)
 
-	// Only stage the changes, don't commit
-	if err := stageChanges(); err != nil {
+	// Only stage the files written for this project, don't commit
+	toStage := []string{projectRelPath(project.Name)}
+	if faviconPath != "" {
+		toStage = append(toStage, logoRelDir(project.Name))
+	}
+	if err := s.stageFiles(toStage...); err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error staging changes: %v", err))
 		return
 	}
@@ -320,36 +516,48 @@ func createProjectHandler(w http.ResponseWriter, r *http.Request) {
 		Message:     "Project created and changes staged",
 		LatestFile:  latestFile,
 		FaviconPath: faviconPath,
+		URLChanges:  urlChanges,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func stageChanges() error {
-	cmd := exec.Command("git", "add", ".")
-	cmd.Dir = "/Users/ahoura/documents/dev-projects/oss-directory"
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("error staging changes: %v\nOutput: %s", err, string(output))
+// stageFiles stages exactly the given paths (relative to the checkout), including
+// deletions, and remembers them as changed by the tool
+func (s *Server) stageFiles(paths ...string) error {
+	if err := s.session.TouchPaths(paths...); err != nil {
+		log.Printf("Warning: Failed to record touched paths: %v", err)
+	}
+
+	stageable := s.stageablePaths(paths)
+	if len(stageable) > 0 {
+		args := append([]string{"add", "--all", "--"}, stageable...)
+		result, err := s.runGit(args...)
+		if err != nil {
+			return fmt.Errorf("error staging files: %v\nOutput: %s", err, result.Stderr)
+		}
 	}
 
-	// Get list of staged files
-	statusCmd := exec.Command("git", "diff", "--cached", "--name-only")
-	statusCmd.Dir = "/Users/ahoura/documents/dev-projects/oss-directory"
-	statusOutput, err := statusCmd.Output()
+	return s.refreshStagedFiles()
+}
+
+// refreshStagedFiles reloads the list of files currently staged in the checkout
+func (s *Server) refreshStagedFiles() error {
+	result, err := s.runGit("diff", "--cached", "--name-only")
 	if err != nil {
 		return fmt.Errorf("error getting staged files: %v", err)
 	}
 
-	mutex.Lock()
-	stagedFiles = strings.Split(strings.TrimSpace(string(statusOutput)), "\n")
-	mutex.Unlock()
+	files := []string{}
+	if output := strings.TrimSpace(result.Stdout); output != "" {
+		files = strings.Split(output, "\n")
+	}
 
-	return nil
+	return s.session.SetStagedFiles(files)
 }
 
-func getStagedFilesHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) getStagedFilesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
 		return
@@ -357,19 +565,17 @@ func getStagedFilesHandler(w http.ResponseWriter, r *http.Request) {
 
 	setCorsHeaders(w)
 
-	mutex.Lock()
 	response := struct {
 		Files []string `json:"files"`
 	}{
-		Files: stagedFiles,
+		Files: s.session.StagedFiles(),
 	}
-	mutex.Unlock()
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-func getCurrentBranchHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) getCurrentBranchHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
 		return
@@ -377,19 +583,16 @@ func getCurrentBranchHandler(w http.ResponseWriter, r *http.Request) {
 
 	setCorsHeaders(w)
 
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	cmd.Dir = "/Users/ahoura/documents/dev-projects/oss-directory"
-	output, err := cmd.Output()
+	currentBranch, err := s.currentBranch()
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error getting current branch: %v", err))
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	currentBranch := strings.TrimSpace(string(output))
-	writeSuccessResponse(w, "Current branch retrieved successfully", currentBranch)
+	s.writeSuccessResponse(w, "Current branch retrieved successfully", currentBranch)
 }
 
-func changeBranchHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) changeBranchHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
 		return
@@ -403,17 +606,24 @@ func changeBranchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cmd := exec.Command("git", "checkout", branchName)
-	cmd.Dir = "/Users/ahoura/documents/dev-projects/oss-directory"
-	output, err := cmd.CombinedOutput()
+	if !validBranchName(branchName) {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("invalid branch name: %q", branchName))
+		return
+	}
+
+	// The trailing "--" makes git read the name as a branch, never as a path
+	result, err := s.runGit("checkout", branchName, "--")
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error changing branch: %v\nOutput: %s", err, string(output)))
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error changing branch: %v\nOutput: %s", err, result.Stderr))
 		return
 	}
 
-	writeSuccessResponse(w, fmt.Sprintf("Successfully changed to branch: %s", branchName), "")
+	// The other branch may contain a different set of projects
+	s.rebuildIndex()
+
+	s.writeSuccessResponse(w, fmt.Sprintf("Successfully changed to branch: %s", branchName), "")
 }
-func getLatestFileHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) getLatestFileHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
 		return
@@ -421,14 +631,10 @@ func getLatestFileHandler(w http.ResponseWriter, r *http.Request) {
 
 	setCorsHeaders(w)
 
-	mutex.Lock()
-	currentLatestFile := latestFile
-	mutex.Unlock()
-
-	writeSuccessResponse(w, "Latest file retrieved successfully", currentLatestFile)
+	s.writeSuccessResponse(w, "Latest file retrieved successfully", s.session.LatestFile())
 }
 
-func getAddedFilesHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) getAddedFilesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
 		return
@@ -436,19 +642,17 @@ func getAddedFilesHandler(w http.ResponseWriter, r *http.Request) {
 
 	setCorsHeaders(w)
 
-	mutex.Lock()
 	response := struct {
 		Files []string `json:"files"`
 	}{
-		Files: addedFiles,
+		Files: s.session.AddedFiles(),
 	}
-	mutex.Unlock()
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-func getFileContentHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) getFileContentHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
 		return
@@ -462,10 +666,18 @@ func getFileContentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gitDir := "/Users/ahoura/documents/dev-projects/oss-directory"
-	filePath := filepath.Join(gitDir, "data", "projects", string(filename[0]), filename)
+	// Only project files can be read, so the filename must be a valid project name
+	filePath, err := s.projectPath(strings.TrimSuffix(filename, ".yaml"))
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	content, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		writeErrorResponse(w, http.StatusNotFound, fmt.Sprintf("File %s does not exist", filename))
+		return
+	}
 	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error reading file: %v", err))
 		return
@@ -475,24 +687,21 @@ func getFileContentHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(content)
 }
 
-func writeSuccessResponse(w http.ResponseWriter, message string, latestFile string) {
-	mutex.Lock()
-	currentStagedFiles := stagedFiles
-	mutex.Unlock()
-
+func (s *Server) writeSuccessResponse(w http.ResponseWriter, message string, latestFile string) {
 	w.WriteHeader(http.StatusOK)
 	response := Response{
 		Message:     message,
 		LatestFile:  latestFile,
-		StagedFiles: currentStagedFiles,
+		StagedFiles: s.session.StagedFiles(),
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
 func setCorsHeaders(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Favicon-Source")
 }
 
 func writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
@@ -502,51 +711,59 @@ func writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func pullFromUpstream() error {
+// writeValidationErrorResponse reports a project that failed validation with a 422 status
+func writeValidationErrorResponse(w http.ResponseWriter, problems []ValidationError) {
+	log.Printf("Project failed validation with %d error(s)", len(problems))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	response := Response{
+		Error:            "Project failed validation",
+		ValidationErrors: problems,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) pullFromUpstream() error {
 	log.Println("Attempting to pull from upstream repository...")
 
 	// First, fetch the latest changes from upstream
-	fetchCmd := exec.Command("git", "fetch", "upstream")
-	fetchCmd.Dir = "/Users/ahoura/documents/dev-projects/oss-directory"
-	fetchOutput, err := fetchCmd.CombinedOutput()
+	fetchResult, err := s.runGit("fetch", s.config.UpstreamRemote)
 	if err != nil {
-		return fmt.Errorf("error fetching from upstream: %v\nOutput: %s", err, string(fetchOutput))
+		return fmt.Errorf("error fetching from upstream: %v\nOutput: %s", err, fetchResult.Stderr)
 	}
-	log.Printf("Fetch from upstream successful. Output: %s", string(fetchOutput))
+	log.Printf("Fetch from upstream successful. Output: %s", fetchResult.Stdout+fetchResult.Stderr)
 
 	// Now, merge the changes into the current branch
-	mergeCmd := exec.Command("git", "merge", "upstream/main")
-	mergeCmd.Dir = "/Users/ahoura/documents/dev-projects/oss-directory"
-	mergeOutput, err := mergeCmd.CombinedOutput()
+	mergeResult, err := s.runGit("merge", s.config.UpstreamRemote+"/"+s.config.UpstreamBranch)
 	if err != nil {
-		return fmt.Errorf("error merging upstream changes: %v\nOutput: %s", err, string(mergeOutput))
+		return fmt.Errorf("error merging upstream changes: %v\nOutput: %s", err, mergeResult.Stdout+mergeResult.Stderr)
 	}
-	log.Printf("Merge from upstream successful. Output: %s", string(mergeOutput))
+	log.Printf("Merge from upstream successful. Output: %s", mergeResult.Stdout)
 
 	log.Println("Successfully pulled and merged changes from upstream repository.")
 	return nil
 }
 
-func resetAddedFiles() {
-	mutex.Lock()
-	addedFiles = []string{} // Clear the added files slice
-	latestFile = ""         // Reset the latest file
-	mutex.Unlock()
+func (s *Server) resetAddedFiles() {
+	// Clear the added files and latest file; the history is kept
+	if err := s.session.ResetFiles(); err != nil {
+		log.Printf("Warning: Failed to persist session state: %v", err)
+	}
 }
 
-func resetFilesHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) resetFilesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
 		return
 	}
 
 	setCorsHeaders(w)
-	resetAddedFiles()
-	writeSuccessResponse(w, "Files reset successfully", "")
+	s.resetAddedFiles()
+	s.writeSuccessResponse(w, "Files reset successfully", "")
 }
 
 // Test endpoint for favicon functionality
-func testFaviconHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) testFaviconHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
 		return
@@ -559,7 +776,7 @@ func testFaviconHandler(w http.ResponseWriter, r *http.Request) {
 	log.SetOutput(&logOutput)
 
 	// Run the test
-	RunTest()
+	RunTest(s.config.DirectoryPath)
 
 	// Reset logger
 	log.SetOutput(os.Stdout)
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// newTestServer returns a server on an empty git checkout
+func newTestServer(t *testing.T, devMode bool) *Server {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	checkout := t.TempDir()
+	gitTest(t, checkout, "init", "-q")
+
+	cfg := DefaultConfig()
+	cfg.DirectoryPath = checkout
+	cfg.StateDir = t.TempDir()
+	cfg.DevMode = devMode
+
+	server, err := NewServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// newLocalSite serves a page with a PNG icon on a loopback address, which is only
+// reachable when fetching insecurely
+func newLocalSite(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var icon bytes.Buffer
+	if err := png.Encode(&icon, image.NewRGBA(image.Rect(0, 0, 16, 16))); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><link rel="icon" href="/icon.png"></head></html>`))
+	})
+	mux.HandleFunc("/icon.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(icon.Bytes())
+	})
+
+	site := httptest.NewServer(mux)
+	t.Cleanup(site.Close)
+	return site
+}
+
+func TestCreateProjectFetchOptions(t *testing.T) {
+	post := func(s *Server, handler http.HandlerFunc, target string, project Project) *httptest.ResponseRecorder {
+		data, err := json.Marshal(project)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodPost, target, bytes.NewReader(data)))
+		return recorder
+	}
+
+	t.Run("insecure outside dev mode", func(t *testing.T) {
+		s := newTestServer(t, false)
+		project := Project{Name: "alpha", DisplayName: "Alpha"}
+
+		for target, handler := range map[string]http.HandlerFunc{
+			"/previewProject?insecure=true": s.previewProjectHandler,
+			"/createProject?insecure=true":  s.createProjectHandler,
+		} {
+			if recorder := post(s, handler, target, project); recorder.Code != http.StatusForbidden {
+				t.Errorf("%s status = %d, want 403", target, recorder.Code)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(s.config.DirectoryPath, projectRelPath("alpha"))); !os.IsNotExist(err) {
+			t.Errorf("project file was written: %v", err)
+		}
+	})
+
+	t.Run("insecure in dev mode", func(t *testing.T) {
+		s := newTestServer(t, true)
+		site := newLocalSite(t)
+		project := Project{Name: "alpha", DisplayName: "Alpha", Websites: []URL{{Url: site.URL}}}
+
+		if recorder := post(s, s.createProjectHandler, "/createProject?insecure=true", project); recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d (%s), want 200", recorder.Code, recorder.Body.String())
+		}
+		if _, err := os.Stat(filepath.Join(s.config.DirectoryPath, logoRelDir("alpha"), "logo.json")); err != nil {
+			t.Errorf("favicon of the local site was not saved: %v", err)
+		}
+	})
+
+	t.Run("secure", func(t *testing.T) {
+		s := newTestServer(t, true)
+		site := newLocalSite(t)
+		project := Project{Name: "alpha", DisplayName: "Alpha", Websites: []URL{{Url: site.URL}}}
+
+		if recorder := post(s, s.createProjectHandler, "/createProject", project); recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d (%s), want 200", recorder.Code, recorder.Body.String())
+		}
+		if _, err := os.Stat(filepath.Join(s.config.DirectoryPath, logoRelDir("alpha"))); !os.IsNotExist(err) {
+			t.Errorf("favicon was fetched from a loopback address without insecure=true: %v", err)
+		}
+	})
+}
+
+func TestChangeBranch(t *testing.T) {
+	s := newTestServer(t, false)
+	checkout := s.config.DirectoryPath
+	writeTestFile(t, checkout, "data/projects/a/alpha.yaml", "version: 7\nname: alpha\n")
+	gitTest(t, checkout, "add", "-A")
+	gitTest(t, checkout, "commit", "-q", "-m", "initial")
+	gitTest(t, checkout, "branch", "feature/alpha")
+
+	changeBranch := func(branch string) int {
+		recorder := httptest.NewRecorder()
+		target := "/changeBranch?branch=" + url.QueryEscape(branch)
+		s.changeBranchHandler(recorder, httptest.NewRequest(http.MethodPost, target, nil))
+		return recorder.Code
+	}
+
+	for _, branch := range []string{"", "-b", "--orphan=evil", "-", "feature/../main", "main~1", "main:x", "a b"} {
+		if status := changeBranch(branch); status != http.StatusBadRequest {
+			t.Errorf("changeBranch(%q) status = %d, want 400", branch, status)
+		}
+	}
+	if current := gitTest(t, checkout, "branch", "--show-current"); current != "main" {
+		t.Fatalf("an invalid name changed the branch to %q", current)
+	}
+
+	if status := changeBranch("missing"); status != http.StatusInternalServerError {
+		t.Errorf("changeBranch(missing) status = %d, want 500", status)
+	}
+	if status := changeBranch("feature/alpha"); status != http.StatusOK {
+		t.Fatalf("changeBranch(feature/alpha) status = %d, want 200", status)
+	}
+	if current := gitTest(t, checkout, "branch", "--show-current"); current != "feature/alpha" {
+		t.Errorf("current branch = %q, want feature/alpha", current)
+	}
+}
+
+func TestGetFaviconHandlerHeaders(t *testing.T) {
+	s := newTestServer(t, false)
+	checkout := s.config.DirectoryPath
+	writeTestFile(t, checkout, logoRelDir("bebop")+"/"+svgLogoFileName, `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)
+
+	for _, query := range []string{"projectName=bebop", "projectName=bebop&size=64"} {
+		recorder := httptest.NewRecorder()
+		s.getFaviconHandler(recorder, httptest.NewRequest(http.MethodGet, "/getFavicon?"+query, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("getFavicon?%s status = %d (%s), want 200", query, recorder.Code, recorder.Body.String())
+		}
+
+		headers := recorder.Header()
+		if got := headers.Get("Content-Type"); got != "image/svg+xml" {
+			t.Errorf("getFavicon?%s Content-Type = %q", query, got)
+		}
+		if got := headers.Get("Content-Security-Policy"); got != "default-src 'none'; style-src 'unsafe-inline'" {
+			t.Errorf("getFavicon?%s Content-Security-Policy = %q", query, got)
+		}
+		if got := headers.Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("getFavicon?%s X-Content-Type-Options = %q", query, got)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// projectNamePattern is the oss-directory slug rule for project and collection names:
+// lowercase letters and digits in groups joined by single hyphens. Every name
+// matching it is its own GenerateSlug.
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// slugInvalidChars matches what GenerateSlug drops
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9-]`)
+
+// errEmptyName is returned for names that are missing or have no usable characters
+var errEmptyName = errors.New("name is required")
+
+// GenerateSlug creates a URL-friendly slug from a project name
+func GenerateSlug(projectName string) string {
+	// Convert to lowercase
+	slug := strings.ToLower(projectName)
+
+	// Replace spaces with hyphens
+	slug = strings.ReplaceAll(slug, " ", "-")
+
+	// Remove special characters
+	slug = slugInvalidChars.ReplaceAllString(slug, "")
+
+	// Remove consecutive hyphens
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+
+	// Trim leading and trailing hyphens
+	slug = strings.Trim(slug, "-")
+
+	return slug
+}
+
+// ValidateProjectName checks that a project or collection name is a slug. Slashes,
+// dots and everything else that could escape the data directories are rejected.
+func ValidateProjectName(name string) error {
+	if name == "" {
+		return errEmptyName
+	}
+	if !projectNamePattern.MatchString(name) {
+		return fmt.Errorf("name must be lowercase letters, digits and single hyphens (e.g. %q)", GenerateSlug(name))
+	}
+	return nil
+}
+
+// projectRelPath returns the location of a project file relative to the checkout,
+// following the oss-directory rule of grouping files by the first character of the
+// name. The name must have passed ValidateProjectName.
+func projectRelPath(name string) string {
+	firstChar := strings.ToLower(name[:min(1, len(name))])
+	return filepath.Join("data", "projects", firstChar, name+".yaml")
+}
+
+// logoRelDir returns the logo directory of a project relative to the checkout
+func logoRelDir(name string) string {
+	return filepath.Join("data", "logos", GenerateSlug(name))
+}
+
+// collectionRelPath returns the location of a collection file relative to the checkout
+func collectionRelPath(name string) string {
+	return filepath.Join("data", "collections", name+".yaml")
+}
+
+// checkoutRelPath validates a path relative to the checkout, typically given by the
+// client, and returns it cleaned with forward slashes. Absolute paths, paths leaving
+// the checkout and paths inside .git are rejected.
+func checkoutRelPath(raw string) (string, error) {
+	if raw == "" {
+		return "", errors.New("file parameter is required")
+	}
+	if filepath.IsAbs(raw) || strings.HasPrefix(raw, "/") || strings.Contains(raw, "\\") {
+		return "", fmt.Errorf("invalid file path: %q", raw)
+	}
+
+	path := filepath.ToSlash(filepath.Clean(raw))
+	if path == "." || path == ".." || strings.HasPrefix(path, "../") || path == ".git" || strings.HasPrefix(path, ".git/") {
+		return "", fmt.Errorf("invalid file path: %q", raw)
+	}
+	return path, nil
+}
+
+// resolveCheckoutPath is the single place relative paths are turned into absolute
+// paths inside a checkout. It fails rather than return a path outside baseDir, also
+// when a symlink in the checkout points elsewhere.
+func resolveCheckoutPath(baseDir, relPath string) (string, error) {
+	clean, err := checkoutRelPath(relPath)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(baseDir, filepath.FromSlash(clean))
+	if !isWithinDir(baseDir, path) {
+		return "", fmt.Errorf("path %q is outside the checkout", relPath)
+	}
+
+	resolvedBase, err := filepath.EvalSymlinks(baseDir)
+	if err != nil {
+		return "", fmt.Errorf("error resolving checkout %s: %v", baseDir, err)
+	}
+	resolved, err := evalExistingSymlinks(path)
+	if err != nil || !isWithinDir(resolvedBase, resolved) {
+		return "", fmt.Errorf("path %q is outside the checkout", relPath)
+	}
+	return path, nil
+}
+
+// isWithinDir reports whether path is dir or below it; both must be clean
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// evalExistingSymlinks resolves the symlinks of the part of path that exists and
+// appends the rest, so paths of files yet to be written can be checked too.
+// Dangling symlinks are an error, as writing through them creates their target.
+func evalExistingSymlinks(path string) (string, error) {
+	existing, rest := path, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if _, statErr := os.Lstat(existing); !errors.Is(statErr, fs.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return "", err
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+// checkoutPath resolves a path relative to the oss-directory checkout
+func (s *Server) checkoutPath(relPath string) (string, error) {
+	return resolveCheckoutPath(s.config.DirectoryPath, relPath)
+}
+
+// projectPath returns the absolute location of a project file in the checkout
+func (s *Server) projectPath(name string) (string, error) {
+	if err := ValidateProjectName(name); err != nil {
+		return "", fmt.Errorf("invalid project name %q: %v", name, err)
+	}
+	return s.checkoutPath(projectRelPath(name))
+}
+
+// collectionPath returns the absolute location of a collection file in the checkout
+func (s *Server) collectionPath(name string) (string, error) {
+	if err := ValidateProjectName(name); err != nil {
+		return "", fmt.Errorf("invalid collection name %q: %v", name, err)
+	}
+	return s.checkoutPath(collectionRelPath(name))
+}
+
+// logosDir returns the absolute logo directory of a project. Names are slugified, as
+// the extension sends display names too, but must keep at least one character.
+func (fh *FaviconHandler) logosDir(projectName string) (string, error) {
+	if GenerateSlug(projectName) == "" {
+		return "", fmt.Errorf("invalid project name %q: %v", projectName, errEmptyName)
+	}
+	return resolveCheckoutPath(fh.BaseDirectory, logoRelDir(projectName))
+}
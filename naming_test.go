@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateProjectName(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"bebop", true},
+		{"bebop-dex", true},
+		{"1inch", true},
+		{"a", true},
+		{"", false},
+		{"Bebop", false},
+		{"bebop dex", false},
+		{"bebop--dex", false},
+		{"-bebop", false},
+		{"bebop-", false},
+		{"bebop_dex", false},
+		{"bebop.yaml", false},
+		{"a/b", false},
+		{"..", false},
+		{"../bebop", false},
+		{`a\b`, false},
+		{".git", false},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateProjectName(tt.name); (err == nil) != tt.ok {
+			t.Errorf("ValidateProjectName(%q) = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+
+	if err := ValidateProjectName(""); !errors.Is(err, errEmptyName) {
+		t.Errorf("ValidateProjectName(\"\") = %v, want errEmptyName", err)
+	}
+}
+
+func TestCheckoutRelPath(t *testing.T) {
+	tests := []struct {
+		raw, path string
+		ok        bool
+	}{
+		{"data/projects/b/bebop.yaml", "data/projects/b/bebop.yaml", true},
+		{"./data/logos/bebop/", "data/logos/bebop", true},
+		{"data/projects/../collections/defi.yaml", "data/collections/defi.yaml", true},
+		{".gitignore", ".gitignore", true},
+		{"", "", false},
+		{".", "", false},
+		{"..", "", false},
+		{"../outside", "", false},
+		{"data/../../outside", "", false},
+		{"/etc/passwd", "", false},
+		{`data\projects\b\bebop.yaml`, "", false},
+		{`..\outside`, "", false},
+		{".git", "", false},
+		{".git/config", "", false},
+		{"data/../.git/hooks/pre-commit", "", false},
+	}
+
+	for _, tt := range tests {
+		path, err := checkoutRelPath(tt.raw)
+		if (err == nil) != tt.ok || path != tt.path {
+			t.Errorf("checkoutRelPath(%q) = %q, %v; want %q, ok=%v", tt.raw, path, err, tt.path, tt.ok)
+		}
+	}
+}
+
+func TestResolveCheckoutPath(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
+	for _, dir := range []string{"data/projects/b", "data/collections"} {
+		if err := os.MkdirAll(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(base, "data/projects/b/bebop.yaml"), []byte("name: bebop\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	symlinks := map[string]string{
+		"data/logos":                 outside,
+		"data/projects/o":            filepath.Join(outside, "o"),
+		"data/projects/b/evil.yaml":  filepath.Join(outside, "evil.yaml"),
+		"data/collections/defi.yaml": filepath.Join(base, "data/projects/b/bebop.yaml"),
+		"data/projects/a":            filepath.Join(base, "data/projects/b"),
+	}
+	for link, target := range symlinks {
+		if err := os.Symlink(target, filepath.Join(base, link)); err != nil {
+			t.Skipf("symlinks are not supported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		rel string
+		ok  bool
+	}{
+		{"data/projects/b/bebop.yaml", true},
+		{"data/projects/n/new/deeper.yaml", true},
+		{"data/collections/defi.yaml", true},
+		{"data/projects/a/alpha.yaml", true},
+		{"", false},
+		{"..", false},
+		{"../outside", false},
+		{"/etc/passwd", false},
+		{`data\projects`, false},
+		{".git/config", false},
+		{"data/logos/bebop/favicon.png", false},
+		{"data/logos", false},
+		{"data/projects/o/other.yaml", false},
+		{"data/projects/b/evil.yaml", false},
+	}
+
+	for _, tt := range tests {
+		path, err := resolveCheckoutPath(base, tt.rel)
+		if (err == nil) != tt.ok {
+			t.Errorf("resolveCheckoutPath(%q) = %q, %v; want ok=%v", tt.rel, path, err, tt.ok)
+			continue
+		}
+		if tt.ok && path != filepath.Join(base, filepath.FromSlash(tt.rel)) {
+			t.Errorf("resolveCheckoutPath(%q) = %q, want it below %s", tt.rel, path, base)
+		}
+	}
+}
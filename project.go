@@ -0,0 +1,46 @@
+package main
+
+// ProjectSchemaVersion is the oss-directory project schema version this tool writes
+const ProjectSchemaVersion = 7
+
+// Project mirrors the oss-directory v7 project schema
+type Project struct {
+	Version        int                 `json:"version" yaml:"version"`
+	Name           string              `json:"name" yaml:"name"`
+	DisplayName    string              `json:"displayName" yaml:"display_name"`
+	Description    string              `json:"description,omitempty" yaml:"description,omitempty"`
+	Websites       []URL               `json:"websites,omitempty" yaml:"websites,omitempty"`
+	Github         []URL               `json:"github,omitempty" yaml:"github,omitempty"`
+	Npm            []URL               `json:"npm,omitempty" yaml:"npm,omitempty"`
+	Crates         []URL               `json:"crates,omitempty" yaml:"crates,omitempty"`
+	Pypi           []URL               `json:"pypi,omitempty" yaml:"pypi,omitempty"`
+	Go             []URL               `json:"go,omitempty" yaml:"go,omitempty"`
+	OpenCollective []URL               `json:"openCollective,omitempty" yaml:"open_collective,omitempty"`
+	Defillama      []URL               `json:"defillama,omitempty" yaml:"defillama,omitempty"`
+	Social         *Social             `json:"social,omitempty" yaml:"social,omitempty"`
+	Blockchain     []BlockchainAddress `json:"blockchain,omitempty" yaml:"blockchain,omitempty"`
+	Comments       []string            `json:"comments,omitempty" yaml:"comments,omitempty"`
+}
+
+type URL struct {
+	Url string `json:"url" yaml:"url"`
+}
+
+type Social struct {
+	Farcaster []URL `json:"farcaster,omitempty" yaml:"farcaster,omitempty"`
+	Medium    []URL `json:"medium,omitempty" yaml:"medium,omitempty"`
+	Mirror    []URL `json:"mirror,omitempty" yaml:"mirror,omitempty"`
+	Telegram  []URL `json:"telegram,omitempty" yaml:"telegram,omitempty"`
+	Twitter   []URL `json:"twitter,omitempty" yaml:"twitter,omitempty"`
+	Discord   []URL `json:"discord,omitempty" yaml:"discord,omitempty"`
+	Youtube   []URL `json:"youtube,omitempty" yaml:"youtube,omitempty"`
+	Linkedin  []URL `json:"linkedin,omitempty" yaml:"linkedin,omitempty"`
+}
+
+// BlockchainAddress is an on-chain address associated with a project
+type BlockchainAddress struct {
+	Address  string   `json:"address" yaml:"address"`
+	Name     string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Networks []string `json:"networks" yaml:"networks"`
+	Tags     []string `json:"tags" yaml:"tags"`
+}
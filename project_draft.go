@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// PageMetadata is what a project's website says about itself
+type PageMetadata struct {
+	Title       string
+	SiteName    string
+	Description string
+	Links       []string // Absolute URLs of every anchor on the page
+}
+
+// DraftResponse is a project pre-filled from a website, for the user to review
+type DraftResponse struct {
+	Project    *Project             `json:"project"`
+	Duplicates []DuplicateCandidate `json:"duplicates,omitempty"`
+}
+
+// Path prefixes of links that point at a platform's own pages rather than a project's profile
+var nonProfilePaths = map[string][]string{
+	"github.com": {"login", "signup", "features", "about", "pricing", "sponsors", "marketplace", "topics", "explore"},
+	"x.com":      {"intent", "share", "home", "search", "hashtag", "i"},
+	"t.me":       {"share"},
+}
+
+// ParsePageMetadata reads the title, Open Graph tags and anchors of an HTML document.
+// Links are resolved against pageURL.
+func ParsePageMetadata(body io.Reader, pageURL *url.URL) (*PageMetadata, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &PageMetadata{}
+	var description string
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "title":
+				if meta.Title == "" && n.FirstChild != nil {
+					meta.Title = strings.TrimSpace(n.FirstChild.Data)
+				}
+			case "meta":
+				key := strings.ToLower(htmlAttr(n, "property"))
+				if key == "" {
+					key = strings.ToLower(htmlAttr(n, "name"))
+				}
+				content := strings.TrimSpace(htmlAttr(n, "content"))
+				switch {
+				case key == "og:site_name" && meta.SiteName == "":
+					meta.SiteName = content
+				case key == "og:description" && meta.Description == "":
+					meta.Description = content
+				case key == "description" && description == "":
+					description = content
+				}
+			case "a":
+				href := strings.TrimSpace(htmlAttr(n, "href"))
+				if resolved, err := pageURL.Parse(href); err == nil && href != "" &&
+					(resolved.Scheme == "http" || resolved.Scheme == "https") {
+					meta.Links = append(meta.Links, resolved.String())
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	// Fall back to the plain description when there is no Open Graph one
+	if meta.Description == "" {
+		meta.Description = description
+	}
+
+	return meta, nil
+}
+
+// DraftProject builds a project from a website's metadata. The display name comes
+// from og:site_name or the page title, the name is its slug, and the first link to
+// each supported GitHub and social profile is filled in.
+func DraftProject(siteURL *url.URL, meta *PageMetadata) *Project {
+	displayName := meta.SiteName
+	if displayName == "" {
+		displayName = siteNameFromTitle(meta.Title)
+	}
+	if displayName == "" {
+		displayName = strings.TrimPrefix(siteURL.Hostname(), "www.")
+	}
+
+	website := &url.URL{Scheme: siteURL.Scheme, Host: siteURL.Host, Path: "/"}
+
+	project := &Project{
+		Version:     ProjectSchemaVersion,
+		Name:        GenerateSlug(displayName),
+		DisplayName: displayName,
+		Description: meta.Description,
+		Websites:    []URL{{Url: strings.TrimSuffix(website.String(), "/")}},
+	}
+
+	social := &Social{}
+	for _, link := range meta.Links {
+		host, segment := urlIdentity(link)
+		if segment == "" || isNonProfilePath(host, segment) {
+			continue
+		}
+
+		switch host {
+		case "github.com":
+			if len(project.Github) == 0 {
+				// Link the organization, not whichever repository the page pointed at
+				project.Github = []URL{{Url: "https://github.com/" + segment}}
+			}
+		case "x.com":
+			social.Twitter = appendFirstURL(social.Twitter, "https://x.com/"+segment)
+		case "t.me":
+			social.Telegram = appendFirstURL(social.Telegram, "https://t.me/"+segment)
+		case "discord.gg":
+			// Only invites become discord.gg links; channel and other URLs are kept as they are
+			social.Discord = appendFirstURL(social.Discord, CanonicalizeURL("social.discord", link))
+		case "mirror.xyz":
+			social.Mirror = appendFirstURL(social.Mirror, mirrorProfileURL(link, segment))
+		}
+	}
+
+	if len(social.Twitter)+len(social.Telegram)+len(social.Discord)+len(social.Mirror) > 0 {
+		project.Social = social
+	}
+
+	return project
+}
+
+// siteNameFromTitle takes the site name out of a title like "Bebop | Trade smarter"
+func siteNameFromTitle(title string) string {
+	for _, separator := range []string{" | ", " - ", " – ", " — ", " · ", ": "} {
+		if i := strings.Index(title, separator); i > 0 {
+			return strings.TrimSpace(title[:i])
+		}
+	}
+	return strings.TrimSpace(title)
+}
+
+// isNonProfilePath reports whether a link's first path segment is a platform page
+func isNonProfilePath(host, segment string) bool {
+	for _, prefix := range nonProfilePaths[host] {
+		if segment == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// mirrorProfileURL keeps the subdomain form of <name>.mirror.xyz links
+func mirrorProfileURL(link, segment string) string {
+	if parsed, err := url.Parse(link); err == nil && strings.HasSuffix(strings.ToLower(parsed.Hostname()), ".mirror.xyz") {
+		return "https://" + segment + ".mirror.xyz"
+	}
+	return "https://mirror.xyz/" + segment
+}
+
+// appendFirstURL keeps only the first link found for a social profile
+func appendFirstURL(urls []URL, link string) []URL {
+	if len(urls) > 0 {
+		return urls
+	}
+	return []URL{{Url: link}}
+}
+
+// fetchPageMetadata downloads a website with the hardened fetch client and parses it
+func fetchPageMetadata(siteURL string, opts FetchOptions) (*url.URL, *PageMetadata, error) {
+	if !strings.HasPrefix(siteURL, "http://") && !strings.HasPrefix(siteURL, "https://") {
+		siteURL = "https://" + siteURL
+	}
+
+	pageURL, err := url.Parse(siteURL)
+	if err != nil || pageURL.Host == "" {
+		return nil, nil, fmt.Errorf("invalid URL %q", siteURL)
+	}
+
+	resp, err := NewFetchClient(opts).Get(pageURL.String())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch website: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to fetch website, status code: %d", resp.StatusCode)
+	}
+	if err := checkContentType(resp, htmlContentTypes); err != nil {
+		return nil, nil, fmt.Errorf("website is not an HTML page: %v", err)
+	}
+
+	finalURL := resp.Request.URL
+	meta, err := ParsePageMetadata(io.LimitReader(resp.Body, maxPageBytes), finalURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse website: %v", err)
+	}
+
+	return finalURL, meta, nil
+}
+
+// draftProjectHandler returns a project pre-filled from the website given in url
+func (s *Server) draftProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	siteURL := r.URL.Query().Get("url")
+	if siteURL == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "URL parameter is required")
+		return
+	}
+
+	opts, err := s.fetchOptions(r)
+	if err != nil {
+		writeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	finalURL, meta, err := fetchPageMetadata(siteURL, opts)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadGateway, fmt.Sprintf("Error fetching website: %v", err))
+		return
+	}
+
+	project := DraftProject(finalURL, meta)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DraftResponse{
+		Project:    project,
+		Duplicates: s.index.FindDuplicates(project),
+	})
+}
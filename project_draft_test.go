@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestDraftProjectDiscordLinks(t *testing.T) {
+	tests := []struct {
+		link, want string
+	}{
+		{"https://discord.gg/bebop", "https://discord.gg/bebop"},
+		{"https://discord.com/invite/bebop", "https://discord.gg/bebop"},
+		{"https://discord.com/channels/123/456", "https://discord.com/channels/123/456"},
+	}
+
+	site, _ := url.Parse("https://bebop.xyz/")
+	for _, tt := range tests {
+		project := DraftProject(site, &PageMetadata{SiteName: "Bebop", Links: []string{tt.link}})
+		if project.Social == nil || len(project.Social.Discord) != 1 {
+			t.Errorf("DraftProject(%q) has no Discord link", tt.link)
+			continue
+		}
+		if got := project.Social.Discord[0].Url; got != tt.want {
+			t.Errorf("DraftProject(%q) Discord = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
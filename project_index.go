@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// IndexedProject is a project file known to the directory index
+type IndexedProject struct {
+	Path    string   `json:"path"` // Relative to the checkout
+	Project *Project `json:"project"`
+}
+
+// ProjectIndex keeps every project of the oss-directory checkout in memory
+type ProjectIndex struct {
+	baseDir string
+
+	mutex    sync.RWMutex
+	projects map[string]*IndexedProject // Keyed by project name
+}
+
+// ProjectListResponse is returned by the listing and search endpoints
+type ProjectListResponse struct {
+	Total    int               `json:"total"`
+	Offset   int               `json:"offset"`
+	Limit    int               `json:"limit"`
+	Projects []*IndexedProject `json:"projects"`
+}
+
+// Pagination limits for the listing and search endpoints
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// NewProjectIndex creates an empty index for the checkout at baseDir
+func NewProjectIndex(baseDir string) *ProjectIndex {
+	return &ProjectIndex{
+		baseDir:  baseDir,
+		projects: make(map[string]*IndexedProject),
+	}
+}
+
+// Rebuild walks data/projects/*/*.yaml and replaces the indexed projects.
+// Files that cannot be parsed are logged and skipped.
+func (idx *ProjectIndex) Rebuild() error {
+	pattern := filepath.Join(idx.baseDir, "data", "projects", "*", "*.yaml")
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("error listing project files: %v", err)
+	}
+
+	projects := make(map[string]*IndexedProject, len(paths))
+	skipped := 0
+	for _, path := range paths {
+		entry, err := idx.load(path)
+		if err != nil {
+			log.Printf("Warning: Skipping project file %s: %v", path, err)
+			skipped++
+			continue
+		}
+		projects[entry.Project.Name] = entry
+	}
+
+	idx.mutex.Lock()
+	idx.projects = projects
+	idx.mutex.Unlock()
+
+	log.Printf("Indexed %d projects (%d skipped)", len(projects), skipped)
+	return nil
+}
+
+// Refresh reloads a single project after it was created, edited or removed
+func (idx *ProjectIndex) Refresh(name string) error {
+	path := filepath.Join(idx.baseDir, projectRelPath(name))
+
+	entry, err := idx.load(path)
+	if os.IsNotExist(err) {
+		idx.mutex.Lock()
+		delete(idx.projects, name)
+		idx.mutex.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	idx.mutex.Lock()
+	idx.projects[name] = entry
+	idx.mutex.Unlock()
+	return nil
+}
+
+// Put adds a project to the index without reading it from disk
+func (idx *ProjectIndex) Put(project *Project) {
+	idx.mutex.Lock()
+	idx.projects[project.Name] = &IndexedProject{Path: projectRelPath(project.Name), Project: project}
+	idx.mutex.Unlock()
+}
+
+// load parses one project file
+func (idx *ProjectIndex) load(path string) (*IndexedProject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var project Project
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("error parsing YAML: %v", err)
+	}
+
+	// Fall back to the file name when the name field is missing
+	if project.Name == "" {
+		project.Name = strings.TrimSuffix(filepath.Base(path), ".yaml")
+	}
+
+	relPath, err := filepath.Rel(idx.baseDir, path)
+	if err != nil {
+		relPath = path
+	}
+
+	return &IndexedProject{Path: relPath, Project: &project}, nil
+}
+
+// Get returns the indexed project with the given name
+func (idx *ProjectIndex) Get(name string) (*IndexedProject, bool) {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	entry, ok := idx.projects[name]
+	return entry, ok
+}
+
+// All returns every indexed project sorted by name
+func (idx *ProjectIndex) All() []*IndexedProject {
+	idx.mutex.RLock()
+	entries := make([]*IndexedProject, 0, len(idx.projects))
+	for _, entry := range idx.projects {
+		entries = append(entries, entry)
+	}
+	idx.mutex.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Project.Name < entries[j].Project.Name
+	})
+	return entries
+}
+
+// Search returns projects whose name, display name, description or any URL contains
+// the query, best matches first. URLs are compared without scheme, www. and trailing slash.
+func (idx *ProjectIndex) Search(query string) []*IndexedProject {
+	text := strings.ToLower(strings.TrimSpace(query))
+	if text == "" {
+		return nil
+	}
+	urlQuery := comparableURL(query)
+
+	type match struct {
+		entry *IndexedProject
+		rank  int
+	}
+	var matches []match
+
+	for _, entry := range idx.All() {
+		project := entry.Project
+		name := strings.ToLower(project.Name)
+		displayName := strings.ToLower(project.DisplayName)
+
+		rank := -1
+		switch {
+		case name == text || displayName == text:
+			rank = 0
+		case strings.HasPrefix(name, text) || strings.HasPrefix(displayName, text):
+			rank = 1
+		case strings.Contains(name, text) || strings.Contains(displayName, text):
+			rank = 2
+		case projectHasURL(project, urlQuery):
+			rank = 3
+		case strings.Contains(strings.ToLower(project.Description), text):
+			rank = 4
+		}
+
+		if rank >= 0 {
+			matches = append(matches, match{entry, rank})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].rank < matches[j].rank
+	})
+
+	results := make([]*IndexedProject, len(matches))
+	for i, m := range matches {
+		results[i] = m.entry
+	}
+	return results
+}
+
+// projectHasURL tells whether any URL of the project contains the comparable URL query
+func projectHasURL(project *Project, urlQuery string) bool {
+	if urlQuery == "" {
+		return false
+	}
+	for _, field := range projectURLFields(project) {
+		for _, u := range field.urls {
+			if strings.Contains(comparableURL(u.Url), urlQuery) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// comparableURL lowercases a URL and strips its scheme, www. prefix and trailing slash
+func comparableURL(raw string) string {
+	u := strings.ToLower(strings.TrimSpace(raw))
+	u = strings.TrimPrefix(u, "https://")
+	u = strings.TrimPrefix(u, "http://")
+	u = strings.TrimPrefix(u, "www.")
+	return strings.TrimSuffix(u, "/")
+}
+
+// paginate returns one page of entries, clamping the offset and limit query parameters
+func paginate(r *http.Request, entries []*IndexedProject) ProjectListResponse {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	page := []*IndexedProject{}
+	if offset < len(entries) {
+		end := offset + limit
+		if end > len(entries) {
+			end = len(entries)
+		}
+		page = entries[offset:end]
+	}
+
+	return ProjectListResponse{
+		Total:    len(entries),
+		Offset:   offset,
+		Limit:    limit,
+		Projects: page,
+	}
+}
+
+// refreshIndex reloads a project in the index, logging instead of failing the request
+func (s *Server) refreshIndex(name string) {
+	if err := s.index.Refresh(name); err != nil {
+		log.Printf("Warning: Failed to refresh project index for %s: %v", name, err)
+	}
+}
+
+// rebuildIndex reloads the whole index after the checkout changed underneath it
+func (s *Server) rebuildIndex() {
+	if err := s.index.Rebuild(); err != nil {
+		log.Printf("Warning: Failed to rebuild project index: %v", err)
+	}
+}
+
+// listProjectsHandler returns a page of every project in the directory
+func (s *Server) listProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	response := paginate(r, s.index.All())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// searchProjectsHandler returns a page of projects matching the q parameter
+func (s *Server) searchProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "q parameter is required")
+		return
+	}
+
+	response := paginate(r, s.index.Search(query))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newTestIndex returns an index built from project files in a temporary checkout
+func newTestIndex(t *testing.T, files map[string]string) *ProjectIndex {
+	t.Helper()
+
+	dir := t.TempDir()
+	for path, content := range files {
+		writeTestFile(t, dir, path, content)
+	}
+	idx := NewProjectIndex(dir)
+	if err := idx.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
+
+// indexNames returns the project names of index entries in order
+func indexNames(entries []*IndexedProject) []string {
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Project.Name)
+	}
+	return names
+}
+
+func TestProjectIndexSearch(t *testing.T) {
+	idx := newTestIndex(t, map[string]string{
+		"data/projects/u/uniswap.yaml":      "name: uniswap\ndisplay_name: Uniswap\nwebsites:\n- url: https://uniswap.org/\n",
+		"data/projects/u/uniswap-v4.yaml":   "name: uniswap-v4\ndisplay_name: Uniswap V4\n",
+		"data/projects/s/sushi.yaml":        "name: sushi\ndisplay_name: SushiSwap\ndescription: A fork of uniswap\n",
+		"data/projects/a/aggregator.yaml":   "name: aggregator\ndisplay_name: Swap Aggregator\ngithub:\n- url: https://github.com/uniswap/interface\n",
+		"data/projects/p/pancake.yaml":      "name: pancake\ndisplay_name: PancakeSwap\nsocial:\n  twitter:\n  - url: https://www.x.com/pancakeswap\n",
+		"data/projects/b/broken.yaml":       "name: [broken\n",
+		"data/projects/n/nameless-one.yaml": "display_name: Nameless\n",
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Exact, then prefix, then substring, then URL, then description matches,
+		// by name within each rank
+		{"uniswap", []string{"uniswap", "uniswap-v4", "aggregator", "sushi"}},
+		{"  UNISWAP V4 ", []string{"uniswap-v4"}},
+		{"swap", []string{"aggregator", "pancake", "sushi", "uniswap", "uniswap-v4"}},
+		{"https://www.uniswap.org", []string{"uniswap"}},
+		{"x.com/pancakeswap/", []string{"pancake"}},
+		{"nameless-one", []string{"nameless-one"}},
+		{"fork", []string{"sushi"}},
+		{"zzz", []string{}},
+		{" ", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := indexNames(idx.Search(tt.query)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	if _, ok := idx.Get("broken"); ok {
+		t.Error("an unparsable file was indexed")
+	}
+}
+
+func TestProjectIndexRefresh(t *testing.T) {
+	idx := newTestIndex(t, map[string]string{
+		"data/projects/a/alpha.yaml": "name: alpha\ndisplay_name: Alpha\n",
+	})
+
+	writeTestFile(t, idx.baseDir, "data/projects/b/bebop.yaml", "name: bebop\ndisplay_name: Bebop\n")
+	if err := idx.Refresh("bebop"); err != nil {
+		t.Fatal(err)
+	}
+	if got := indexNames(idx.All()); !reflect.DeepEqual(got, []string{"alpha", "bebop"}) {
+		t.Errorf("All() = %q after adding bebop", got)
+	}
+
+	writeTestFile(t, idx.baseDir, "data/projects/a/alpha.yaml", "name: [broken\n")
+	if err := idx.Refresh("alpha"); err == nil {
+		t.Error("Refresh() of an unparsable file succeeded")
+	}
+	if entry, ok := idx.Get("alpha"); !ok || entry.Project.DisplayName != "Alpha" {
+		t.Error("a failed refresh dropped the indexed project")
+	}
+
+	if err := idx.Refresh("gamma"); err != nil {
+		t.Errorf("Refresh() of a missing project error = %v", err)
+	}
+	if _, ok := idx.Get("gamma"); ok {
+		t.Error("a missing project was indexed")
+	}
+}
+
+func TestSearchProjectsHandler(t *testing.T) {
+	s := newTestServer(t, false)
+	for _, name := range []string{"swap-a", "swap-b", "swap-c", "other"} {
+		writeTestFile(t, s.config.DirectoryPath, projectRelPath(name), "name: "+name+"\ndisplay_name: "+name+"\n")
+	}
+	s.rebuildIndex()
+
+	tests := []struct {
+		query  string
+		status int
+		total  int
+		names  []string
+	}{
+		{"q=swap", http.StatusOK, 3, []string{"swap-a", "swap-b", "swap-c"}},
+		{"q=swap&offset=1&limit=1", http.StatusOK, 3, []string{"swap-b"}},
+		{"q=swap&offset=10", http.StatusOK, 3, []string{}},
+		{"q=swap&offset=-1&limit=0", http.StatusOK, 3, []string{"swap-a", "swap-b", "swap-c"}},
+		{"q=", http.StatusBadRequest, 0, nil},
+	}
+
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		s.searchProjectsHandler(recorder, httptest.NewRequest(http.MethodGet, "/searchProjects?"+tt.query, nil))
+		if recorder.Code != tt.status {
+			t.Errorf("searchProjects?%s status = %d, want %d", tt.query, recorder.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+
+		var response ProjectListResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Total != tt.total || !reflect.DeepEqual(indexNames(response.Projects), tt.names) {
+			t.Errorf("searchProjects?%s = %d %q, want %d %q", tt.query, response.Total, indexNames(response.Projects), tt.total, tt.names)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	s.listProjectsHandler(recorder, httptest.NewRequest(http.MethodGet, "/listProjects?limit=1000", nil))
+	var response ProjectListResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Limit != maxPageSize || response.Total != 4 {
+		t.Errorf("listProjects limit %d, total %d; want %d, 4", response.Limit, response.Total, maxPageSize)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// prepareProject applies the normalization and validation every new project goes
+// through and returns the YAML that would be written. The YAML is only returned
+// when the project is valid.
+func (s *Server) prepareProject(project *Project) ([]byte, []URLChange, []ValidationError, error) {
+	project.Version = ProjectSchemaVersion
+	urlChanges := CanonicalizeProject(project)
+
+	if problems := s.validator.Validate(project); len(problems) > 0 {
+		return nil, urlChanges, problems, nil
+	}
+
+	data, err := yaml.Marshal(project)
+	if err != nil {
+		return nil, urlChanges, nil, fmt.Errorf("error marshalling YAML: %v", err)
+	}
+	return data, urlChanges, nil, nil
+}
+
+// FaviconPreview describes the logo that creating a project would save
+type FaviconPreview struct {
+	SourceURL   string `json:"sourceUrl"`
+	Path        string `json:"path"` // Where the logo would be stored, relative to the checkout
+	ContentType string `json:"contentType"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	DataURL     string `json:"dataUrl"`
+}
+
+// ProjectPreview is what creating a project would do, computed without writing anything
+type ProjectPreview struct {
+	Path             string               `json:"path"`
+	YAML             string               `json:"yaml,omitempty"`
+	Exists           bool                 `json:"exists"`
+	ValidationErrors []ValidationError    `json:"validationErrors,omitempty"`
+	Duplicates       []DuplicateCandidate `json:"duplicates,omitempty"`
+	URLChanges       []URLChange          `json:"urlChanges,omitempty"`
+	Favicon          *FaviconPreview      `json:"favicon,omitempty"`
+	FaviconError     string               `json:"faviconError,omitempty"`
+}
+
+// previewFavicon fetches the favicon createProject would save for a website
+func previewFavicon(fh *FaviconHandler, projectName, websiteURL string, opts FetchOptions) (*FaviconPreview, error) {
+	data, sourceURL, err := fh.FetchFavicon(websiteURL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	favicon, err := DecodeFavicon(data)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := favicon.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := faviconFileName
+	if favicon.IsSVG() {
+		fileName = svgLogoFileName
+	}
+
+	preview := &FaviconPreview{
+		SourceURL:   sourceURL,
+		Path:        filepath.Join(logoRelDir(projectName), fileName),
+		ContentType: favicon.ContentType(),
+		DataURL:     "data:" + favicon.ContentType() + ";base64," + base64.StdEncoding.EncodeToString(stored),
+	}
+	if favicon.Image != nil {
+		bounds := favicon.Image.Bounds()
+		preview.Width, preview.Height = bounds.Dx(), bounds.Dy()
+	}
+	return preview, nil
+}
+
+// previewProjectHandler returns the YAML, target path, favicon, validation problems
+// and likely duplicates of a project as /createProject would produce them, without
+// touching the checkout. Pass favicon=false to skip fetching the favicon.
+func (s *Server) previewProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	var project Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %v", err))
+		return
+	}
+
+	opts, err := s.fetchOptions(r)
+	if err != nil {
+		writeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	data, urlChanges, problems, err := s.prepareProject(&project)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	preview := ProjectPreview{
+		YAML:             string(data),
+		ValidationErrors: problems,
+		URLChanges:       urlChanges,
+		Duplicates:       s.index.FindDuplicates(&project),
+	}
+
+	// The target path is only meaningful for a usable name
+	if filePath, err := s.projectPath(project.Name); err == nil {
+		preview.Path = projectRelPath(project.Name)
+		if _, err := os.Stat(filePath); err == nil {
+			preview.Exists = true
+		}
+	}
+
+	if r.URL.Query().Get("favicon") != "false" && len(project.Websites) > 0 && project.Websites[0].Url != "" && project.Name != "" {
+		favicon, err := previewFavicon(s.favicons, project.Name, project.Websites[0].Url, opts)
+		if err != nil {
+			preview.FaviconError = err.Error()
+		} else {
+			preview.Favicon = favicon
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
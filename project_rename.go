@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	errProjectNotFound = errors.New("project does not exist")
+	errRenameConflict  = errors.New("target already exists")
+)
+
+// RenameResult lists what a project rename moved and rewrote, as paths relative to the checkout
+type RenameResult struct {
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Path        string   `json:"path"`
+	Logo        string   `json:"logo,omitempty"`
+	Collections []string `json:"collections,omitempty"`
+}
+
+// renameUndo reverts one applied step of a rename
+type renameUndo func() error
+
+// movePath moves a file or directory with git mv, so git records the rename. Paths
+// git does not know about, e.g. a logo that was unstaged, are moved on disk.
+func (s *Server) movePath(from, to string) error {
+	toPath, err := s.checkoutPath(to)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+		return fmt.Errorf("error creating directory: %v", err)
+	}
+
+	if tracked, err := s.runGit("ls-files", "--", from); err == nil && strings.TrimSpace(tracked.Stdout) == "" {
+		fromPath, err := s.checkoutPath(from)
+		if err != nil {
+			return err
+		}
+		return os.Rename(fromPath, toPath)
+	}
+
+	if result, err := s.runGit("mv", "--", from, to); err != nil {
+		return fmt.Errorf("error moving %s: %v\nOutput: %s", from, err, result.Stderr)
+	}
+	return nil
+}
+
+// writeCheckoutFile replaces a file of the checkout, returning how to restore its content
+func (s *Server) writeCheckoutFile(relPath string, data []byte) (renameUndo, error) {
+	path, err := s.checkoutPath(relPath)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, fmt.Errorf("error writing file: %v", err)
+	}
+	return func() error { return os.WriteFile(path, previous, 0644) }, nil
+}
+
+// saveIndexEntries records the index entries under paths, returning how to put them back
+func (s *Server) saveIndexEntries(paths []string) (renameUndo, error) {
+	result, err := s.runGit(append([]string{"ls-files", "--stage", "-z", "--"}, paths...)...)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the index: %v\nOutput: %s", err, result.Stderr)
+	}
+
+	// Each entry is "<mode> <object> <stage>\t<path>"
+	var entries []string
+	for _, entry := range strings.Split(strings.TrimSuffix(result.Stdout, "\x00"), "\x00") {
+		info, path, ok := strings.Cut(entry, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 3 {
+			continue
+		}
+		entries = append(entries, fields[0]+","+fields[1]+","+path)
+	}
+
+	return func() error {
+		args := append([]string{"rm", "--cached", "-r", "-q", "--ignore-unmatch", "--"}, paths...)
+		if result, err := s.runGit(args...); err != nil {
+			return fmt.Errorf("error resetting the index: %v\nOutput: %s", err, result.Stderr)
+		}
+		for _, entry := range entries {
+			if result, err := s.runGit("update-index", "--add", "--cacheinfo", entry); err != nil {
+				return fmt.Errorf("error restoring the index: %v\nOutput: %s", err, result.Stderr)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// RenameProject renames a project: its file is moved to the path of the new name and
+// its name field updated, its logo directory is moved to the new slug, and every
+// collection listing it is rewritten. Either all of it happens or, if a step fails,
+// the completed steps are reverted. The result is staged.
+func (s *Server) RenameProject(from, to string) (*RenameResult, error) {
+	fromPath, err := s.projectPath(from)
+	if err != nil {
+		return nil, err
+	}
+	toPath, err := s.projectPath(to)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return nil, fmt.Errorf("%w: %s is already the project's name", errRenameConflict, to)
+	}
+
+	doc, err := readProjectDocument(fromPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", errProjectNotFound, from)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading project file: %v", err)
+	}
+	if _, err := os.Stat(toPath); err == nil {
+		return nil, fmt.Errorf("%w: %s", errRenameConflict, projectRelPath(to))
+	}
+
+	data, err := yaml.Marshal(setDocumentKey(doc, "name", to))
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling YAML: %v", err)
+	}
+
+	result := &RenameResult{From: from, To: to, Path: projectRelPath(to)}
+
+	// Plan every step before touching anything, so conflicts fail the rename up front
+	fromLogo, toLogo := logoRelDir(from), logoRelDir(to)
+	fromLogoPath, err := s.favicons.logosDir(from)
+	if err != nil {
+		return nil, err
+	}
+	toLogoPath, err := s.favicons.logosDir(to)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(fromLogoPath); err == nil {
+		if _, err := os.Stat(toLogoPath); err == nil {
+			return nil, fmt.Errorf("%w: %s", errRenameConflict, toLogo)
+		}
+		result.Logo = toLogo
+	}
+
+	collections, err := s.listCollections()
+	if err != nil {
+		return nil, fmt.Errorf("error listing collections: %v", err)
+	}
+	collectionData := make(map[string][]byte)
+	for _, collection := range collections {
+		if !slices.Contains(collection.Projects, from) {
+			continue
+		}
+		collectionDoc, _, err := s.readCollection(collection.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading collection %s: %v", collection.Name, err)
+		}
+		projects := slices.DeleteFunc(collection.Projects, func(p string) bool { return p == from })
+		projects = append(projects, to)
+		updated, err := yaml.Marshal(setDocumentKey(collectionDoc, "projects", sortedProjectNames(projects)))
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling YAML: %v", err)
+		}
+		collectionData[collection.Name] = updated
+		result.Collections = append(result.Collections, collection.Name)
+	}
+
+	var undo []renameUndo
+	rollback := func(cause error) error {
+		for i := len(undo) - 1; i >= 0; i-- {
+			if err := undo[i](); err != nil {
+				log.Printf("Warning: Failed to revert rename of %s: %v", from, err)
+			}
+		}
+		return cause
+	}
+
+	if err := s.movePath(projectRelPath(from), projectRelPath(to)); err != nil {
+		return nil, err
+	}
+	undo = append(undo, func() error { return s.movePath(projectRelPath(to), projectRelPath(from)) })
+
+	restore, err := s.writeCheckoutFile(projectRelPath(to), data)
+	if err != nil {
+		return nil, rollback(err)
+	}
+	undo = append(undo, restore)
+
+	if result.Logo != "" {
+		if err := s.movePath(fromLogo, toLogo); err != nil {
+			return nil, rollback(err)
+		}
+		undo = append(undo, func() error { return s.movePath(toLogo, fromLogo) })
+	}
+
+	for _, name := range result.Collections {
+		restore, err := s.writeCheckoutFile(collectionRelPath(name), collectionData[name])
+		if err != nil {
+			return nil, rollback(fmt.Errorf("error updating collection %s: %v", name, err))
+		}
+		undo = append(undo, restore)
+	}
+
+	paths := []string{projectRelPath(from), projectRelPath(to)}
+	if result.Logo != "" {
+		paths = append(paths, fromLogo, toLogo)
+	}
+	for _, name := range result.Collections {
+		paths = append(paths, collectionRelPath(name))
+	}
+	restoreIndex, err := s.saveIndexEntries(paths)
+	if err != nil {
+		return nil, rollback(err)
+	}
+	if err := s.stageFiles(paths...); err != nil {
+		// git add may have staged some of the paths before failing
+		undo = append(undo, restoreIndex)
+		return nil, rollback(fmt.Errorf("error staging changes: %v", err))
+	}
+
+	s.refreshIndex(from)
+	s.refreshIndex(to)
+
+	return result, nil
+}
+
+// RenameResponse reports a project rename
+type RenameResponse struct {
+	Message     string        `json:"message"`
+	Rename      *RenameResult `json:"rename"`
+	StagedFiles []string      `json:"stagedFiles,omitempty"`
+}
+
+// renameProjectHandler renames the project given as from to the name given as to
+func (s *Server) renameProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if err := ValidateProjectName(from); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid from parameter: %v", err))
+		return
+	}
+	if err := ValidateProjectName(to); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid to parameter: %v", err))
+		return
+	}
+
+	result, err := s.RenameProject(from, to)
+	switch {
+	case errors.Is(err, errProjectNotFound):
+		writeErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, errRenameConflict):
+		writeErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error renaming project: %v", err))
+		return
+	}
+
+	s.recordFile(fmt.Sprintf("%s.yaml", to), actionRenamed)
+
+	log.Printf("Renamed project %s to %s", from, to)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RenameResponse{
+		Message:     fmt.Sprintf("Renamed %s to %s and staged the changes", from, to),
+		Rename:      result,
+		StagedFiles: s.session.StagedFiles(),
+	})
+}
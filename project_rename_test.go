@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newRenameFixture returns a server on a checkout with the committed project bebop,
+// its logo, the project alpha and a collection listing both
+func newRenameFixture(t *testing.T) *Server {
+	t.Helper()
+
+	s := newTestServer(t, false)
+	checkout := s.config.DirectoryPath
+	writeTestFile(t, checkout, "data/projects/b/bebop.yaml", "version: 7\nname: bebop\ndisplay_name: Bebop\nwebsites:\n- url: https://bebop.xyz\n")
+	writeTestFile(t, checkout, "data/projects/a/alpha.yaml", "version: 7\nname: alpha\ndisplay_name: Alpha\n")
+	writeTestFile(t, checkout, "data/logos/bebop/favicon.png", "png")
+	writeTestFile(t, checkout, "data/collections/defi.yaml", "version: 7\nname: defi\ndisplay_name: DeFi\nprojects:\n- alpha\n- bebop\nnotes: kept\n")
+	writeTestFile(t, checkout, "data/collections/other.yaml", "version: 7\nname: other\ndisplay_name: Other\nprojects:\n- alpha\n")
+	gitTest(t, checkout, "add", "-A")
+	gitTest(t, checkout, "commit", "-q", "-m", "initial")
+	s.rebuildIndex()
+	return s
+}
+
+// checkoutSnapshot returns the content of every file in the checkout outside .git
+func checkoutSnapshot(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestRenameProject(t *testing.T) {
+	s := newRenameFixture(t)
+	checkout := s.config.DirectoryPath
+
+	result, err := s.RenameProject("bebop", "bebop-dex")
+	if err != nil {
+		t.Fatalf("RenameProject() error = %v", err)
+	}
+	if result.Path != "data/projects/b/bebop-dex.yaml" || result.Logo != "data/logos/bebop-dex" || len(result.Collections) != 1 || result.Collections[0] != "defi" {
+		t.Errorf("result = %+v", result)
+	}
+
+	files := checkoutSnapshot(t, checkout)
+	if _, ok := files["data/projects/b/bebop.yaml"]; ok {
+		t.Error("old project file still exists")
+	}
+	if got := files["data/projects/b/bebop-dex.yaml"]; got != "version: 7\nname: bebop-dex\ndisplay_name: Bebop\nwebsites:\n- url: https://bebop.xyz\n" {
+		t.Errorf("renamed project file = %q", got)
+	}
+	if files["data/logos/bebop-dex/favicon.png"] != "png" {
+		t.Errorf("logo was not moved: %v", files)
+	}
+	if got := files["data/collections/defi.yaml"]; got != "version: 7\nname: defi\ndisplay_name: DeFi\nprojects:\n- alpha\n- bebop-dex\nnotes: kept\n" {
+		t.Errorf("collection = %q", got)
+	}
+
+	staged := gitTest(t, checkout, "diff", "--cached", "--name-status", "-M")
+	for _, want := range []string{
+		"R100\tdata/logos/bebop/favicon.png\tdata/logos/bebop-dex/favicon.png",
+		"data/projects/b/bebop.yaml\tdata/projects/b/bebop-dex.yaml",
+		"M\tdata/collections/defi.yaml",
+	} {
+		if !strings.Contains(staged, want) {
+			t.Errorf("staged changes %q are missing %q", staged, want)
+		}
+	}
+	if unstaged := gitTest(t, checkout, "diff", "--name-only"); unstaged != "" {
+		t.Errorf("unstaged changes left: %q", unstaged)
+	}
+
+	if _, ok := s.index.Get("bebop"); ok {
+		t.Error("the index still has the old name")
+	}
+	if _, ok := s.index.Get("bebop-dex"); !ok {
+		t.Error("the index is missing the new name")
+	}
+}
+
+func TestRenameProjectRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, checkout string)
+		from    string
+		to      string
+		wantErr error
+	}{
+		{"missing project", nil, "gamma", "delta", errProjectNotFound},
+		{"same name", nil, "bebop", "bebop", errRenameConflict},
+		{"target project exists", nil, "bebop", "alpha", errRenameConflict},
+		{"target logo exists", func(t *testing.T, checkout string) {
+			writeTestFile(t, checkout, "data/logos/bebop-dex/favicon.png", "other")
+		}, "bebop", "bebop-dex", errRenameConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRenameFixture(t)
+			checkout := s.config.DirectoryPath
+			if tt.setup != nil {
+				tt.setup(t, checkout)
+			}
+			before := checkoutSnapshot(t, checkout)
+
+			if _, err := s.RenameProject(tt.from, tt.to); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RenameProject(%s, %s) error = %v, want %v", tt.from, tt.to, err, tt.wantErr)
+			}
+			if after := checkoutSnapshot(t, checkout); !maps.Equal(before, after) {
+				t.Errorf("checkout changed:\nbefore %v\nafter  %v", before, after)
+			}
+		})
+	}
+}
+
+func TestRenameProjectHandlerStatus(t *testing.T) {
+	s := newRenameFixture(t)
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"from=bebop&to=Bebop", http.StatusBadRequest},
+		{"from=../bebop&to=bebop-dex", http.StatusBadRequest},
+		{"from=gamma&to=delta", http.StatusNotFound},
+		{"from=bebop&to=alpha", http.StatusConflict},
+		{"from=bebop&to=bebop-dex", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		s.renameProjectHandler(recorder, httptest.NewRequest(http.MethodPost, "/renameProject?"+tt.query, nil))
+		if recorder.Code != tt.status {
+			t.Errorf("renameProject?%s status = %d (%s), want %d", tt.query, recorder.Code, recorder.Body.String(), tt.status)
+		}
+	}
+}
+
+func TestRenameProjectRollsBack(t *testing.T) {
+	t.Run("collection cannot be written", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("file permissions are not enforced for root")
+		}
+		s := newRenameFixture(t)
+		checkout := s.config.DirectoryPath
+		collection := filepath.Join(checkout, "data/collections/defi.yaml")
+		if err := os.Chmod(collection, 0444); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chmod(collection, 0644) })
+		assertRenameRolledBack(t, s)
+	})
+
+	t.Run("staging fails", func(t *testing.T) {
+		s := newRenameFixture(t)
+		checkout := s.config.DirectoryPath
+		// An untracked logo in an ignored directory is moved on disk but cannot be
+		// added, so git add fails after staging the other paths
+		gitTest(t, checkout, "rm", "-r", "-q", "--cached", "data/logos")
+		gitTest(t, checkout, "commit", "-q", "-m", "untrack logos")
+		writeTestFile(t, filepath.Join(checkout, ".git"), "info/exclude", "data/logos/\n")
+		assertRenameRolledBack(t, s)
+	})
+}
+
+// assertRenameRolledBack renames bebop, expecting it to fail and leave the checkout,
+// the index and the project index as they were
+func assertRenameRolledBack(t *testing.T, s *Server) {
+	t.Helper()
+
+	checkout := s.config.DirectoryPath
+	before := checkoutSnapshot(t, checkout)
+
+	if _, err := s.RenameProject("bebop", "bebop-dex"); err == nil {
+		t.Fatal("RenameProject() succeeded, want an error")
+	}
+
+	if after := checkoutSnapshot(t, checkout); !maps.Equal(before, after) {
+		t.Errorf("checkout was not restored:\nbefore %v\nafter  %v", before, after)
+	}
+	if status := gitTest(t, checkout, "status", "--porcelain"); status != "" {
+		t.Errorf("git status after rollback = %q, want clean", status)
+	}
+	if staged := gitTest(t, checkout, "diff", "--cached", "--name-only"); staged != "" {
+		t.Errorf("staged after rollback: %q", staged)
+	}
+	if _, ok := s.index.Get("bebop"); !ok {
+		t.Error("the index lost the project")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const testFullProjectYAML = `version: 7
+name: bebop
+display_name: Bebop
+description: A DEX aggregator
+websites:
+- url: https://bebop.xyz
+github:
+- url: https://github.com/bebop-dex
+npm:
+- url: https://www.npmjs.com/package/@bebop/sdk
+crates:
+- url: https://crates.io/crates/bebop
+pypi:
+- url: https://pypi.org/project/bebop
+go:
+- url: https://pkg.go.dev/github.com/bebop-dex/go-sdk
+open_collective:
+- url: https://opencollective.com/bebop
+defillama:
+- url: https://defillama.com/protocol/bebop
+social:
+  farcaster:
+  - url: https://warpcast.com/bebop
+  medium:
+  - url: https://medium.com/@bebop
+  mirror:
+  - url: https://mirror.xyz/bebop.eth
+  telegram:
+  - url: https://t.me/bebop
+  twitter:
+  - url: https://x.com/bebop_dex
+  discord:
+  - url: https://discord.gg/bebop
+  youtube:
+  - url: https://www.youtube.com/@bebop
+  linkedin:
+  - url: https://www.linkedin.com/company/bebop
+blockchain:
+- address: "0x0000000000000000000000000000000000000001"
+  name: router
+  networks:
+  - mainnet
+  - optimism
+  tags:
+  - contract
+comments:
+- imported from a spreadsheet
+`
+
+func TestProjectYAMLRoundTrip(t *testing.T) {
+	var project Project
+	if err := yaml.UnmarshalStrict([]byte(testFullProjectYAML), &project); err != nil {
+		t.Fatalf("a full v7 project does not decode strictly: %v", err)
+	}
+
+	if project.Version != ProjectSchemaVersion || project.OpenCollective[0].Url != "https://opencollective.com/bebop" {
+		t.Errorf("project = %+v", project)
+	}
+	if project.Social == nil || project.Social.Linkedin[0].Url != "https://www.linkedin.com/company/bebop" {
+		t.Errorf("social = %+v", project.Social)
+	}
+	want := []BlockchainAddress{{
+		Address:  "0x0000000000000000000000000000000000000001",
+		Name:     "router",
+		Networks: []string{"mainnet", "optimism"},
+		Tags:     []string{"contract"},
+	}}
+	if !reflect.DeepEqual(project.Blockchain, want) {
+		t.Errorf("blockchain = %+v, want %+v", project.Blockchain, want)
+	}
+
+	data, err := yaml.Marshal(&project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != testFullProjectYAML {
+		t.Errorf("marshalled project =\n%s\nwant\n%s", data, testFullProjectYAML)
+	}
+}
+
+func TestProjectYAMLOmitsEmptyFields(t *testing.T) {
+	data, err := yaml.Marshal(&Project{
+		Version:     ProjectSchemaVersion,
+		Name:        "bebop",
+		DisplayName: "Bebop",
+		Blockchain:  []BlockchainAddress{{Address: "0x1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Networks and tags are required by the schema, so they are written even when empty
+	want := "version: 7\nname: bebop\ndisplay_name: Bebop\nblockchain:\n- address: \"0x1\"\n  networks: []\n  tags: []\n"
+	if string(data) != want {
+		t.Errorf("marshalled project =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestProjectJSONKeys(t *testing.T) {
+	data, err := json.Marshal(&Project{
+		Version:        ProjectSchemaVersion,
+		Name:           "bebop",
+		DisplayName:    "Bebop",
+		OpenCollective: []URL{{Url: "https://opencollective.com/bebop"}},
+		Social:         &Social{Twitter: []URL{{Url: "https://x.com/bebop_dex"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"version":7,"name":"bebop","displayName":"Bebop","openCollective":[{"url":"https://opencollective.com/bebop"}],"social":{"twitter":[{"url":"https://x.com/bebop_dex"}]}}`
+	if string(data) != want {
+		t.Errorf("JSON = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// projectYAMLKeys maps the JSON field names of Project to their YAML keys, so merge
+// patches may use either spelling (e.g. displayName or display_name)
+var projectYAMLKeys = buildProjectYAMLKeys()
+
+func buildProjectYAMLKeys() map[string]string {
+	keys := make(map[string]string)
+	projectType := reflect.TypeOf(Project{})
+	for i := 0; i < projectType.NumField(); i++ {
+		field := projectType.Field(i)
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		yamlName := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		keys[jsonName] = yamlName
+		keys[yamlName] = yamlName
+	}
+	return keys
+}
+
+// isKnownProjectKey tells whether a YAML key is modelled by the Project type
+func isKnownProjectKey(key string) bool {
+	yamlKey, ok := projectYAMLKeys[key]
+	return ok && yamlKey == key
+}
+
+// readProjectDocument loads a project file as an ordered YAML document
+func readProjectDocument(path string) (yaml.MapSlice, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing YAML: %v", err)
+	}
+	return doc, nil
+}
+
+// documentToProject decodes an ordered YAML document into a Project, ignoring unknown fields
+func documentToProject(doc yaml.MapSlice) (*Project, error) {
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling YAML: %v", err)
+	}
+
+	var project Project
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("error decoding project: %v", err)
+	}
+	return &project, nil
+}
+
+// projectToDocument encodes a Project as an ordered YAML document
+func projectToDocument(project *Project) (yaml.MapSlice, error) {
+	data, err := yaml.Marshal(project)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling YAML: %v", err)
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing YAML: %v", err)
+	}
+	return doc, nil
+}
+
+// replaceDocument replaces every known field of doc with the fields of replacement.
+// Existing keys keep their position, unknown fields are preserved and new keys are appended.
+func replaceDocument(doc, replacement yaml.MapSlice) yaml.MapSlice {
+	values := make(map[interface{}]interface{}, len(replacement))
+	for _, item := range replacement {
+		values[item.Key] = item.Value
+	}
+
+	var result yaml.MapSlice
+	used := make(map[interface{}]bool)
+	for _, item := range doc {
+		if value, ok := values[item.Key]; ok {
+			result = append(result, yaml.MapItem{Key: item.Key, Value: value})
+			used[item.Key] = true
+			continue
+		}
+		if key, ok := item.Key.(string); ok && isKnownProjectKey(key) {
+			continue // Known field missing from the replacement is removed
+		}
+		result = append(result, item)
+	}
+
+	for _, item := range replacement {
+		if !used[item.Key] {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// mergePatchDocument applies an RFC 7396 JSON merge patch to an ordered YAML document.
+// Top-level keys may use either the JSON or the YAML field names of Project.
+func mergePatchDocument(doc yaml.MapSlice, patch map[string]interface{}) yaml.MapSlice {
+	normalized := make(map[string]interface{}, len(patch))
+	for key, value := range patch {
+		if yamlKey, ok := projectYAMLKeys[key]; ok {
+			key = yamlKey
+		}
+		normalized[key] = value
+	}
+	return mergePatch(doc, normalized)
+}
+
+func mergePatch(doc yaml.MapSlice, patch map[string]interface{}) yaml.MapSlice {
+	result := make(yaml.MapSlice, 0, len(doc))
+	applied := make(map[string]bool)
+
+	for _, item := range doc {
+		key := fmt.Sprint(item.Key)
+		value, ok := patch[key]
+		if !ok {
+			result = append(result, item)
+			continue
+		}
+		applied[key] = true
+
+		if value == nil {
+			continue // null removes the field
+		}
+		if patchObject, isObject := value.(map[string]interface{}); isObject {
+			if existing, isMap := item.Value.(yaml.MapSlice); isMap {
+				result = append(result, yaml.MapItem{Key: item.Key, Value: mergePatch(existing, patchObject)})
+				continue
+			}
+		}
+		result = append(result, yaml.MapItem{Key: item.Key, Value: yamlValue(value)})
+	}
+
+	// Keys that are new to the document are appended in a stable order
+	var newKeys []string
+	for key, value := range patch {
+		if !applied[key] && value != nil {
+			newKeys = append(newKeys, key)
+		}
+	}
+	sort.Strings(newKeys)
+	for _, key := range newKeys {
+		value := patch[key]
+		if patchObject, isObject := value.(map[string]interface{}); isObject {
+			result = append(result, yaml.MapItem{Key: key, Value: mergePatch(nil, patchObject)})
+			continue
+		}
+		result = append(result, yaml.MapItem{Key: key, Value: yamlValue(value)})
+	}
+
+	return result
+}
+
+// yamlValue converts a decoded JSON value into its ordered YAML equivalent
+func yamlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return mergePatch(nil, v)
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = yamlValue(item)
+		}
+		return converted
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	}
+	return value
+}
+
+// updateProjectHandler edits an existing project file. PATCH applies a JSON merge patch,
+// PUT replaces every known field; field order and unknown fields are preserved either way.
+func (s *Server) updateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPatch && r.Method != http.MethodPut {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	name := r.URL.Query().Get("name")
+	filePath, err := s.projectPath(name)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	doc, err := readProjectDocument(filePath)
+	if os.IsNotExist(err) {
+		writeErrorResponse(w, http.StatusNotFound, fmt.Sprintf("Project %s does not exist", name))
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error reading project file: %v", err))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error reading request body: %v", err))
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var replacement Project
+		if err := json.Unmarshal(body, &replacement); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %v", err))
+			return
+		}
+		if replacement.Name == "" {
+			replacement.Name = name
+		}
+		replacement.Version = ProjectSchemaVersion
+
+		replacementDoc, err := projectToDocument(&replacement)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		doc = replaceDocument(doc, replacementDoc)
+	} else {
+		var patch map[string]interface{}
+		if err := json.Unmarshal(body, &patch); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON merge patch: %v", err))
+			return
+		}
+		doc = mergePatchDocument(doc, patch)
+	}
+
+	project, err := documentToProject(doc)
+	if err != nil {
+		writeErrorResponse(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if project.Name != name {
+		writeErrorResponse(w, http.StatusBadRequest, "Updating a project cannot change its name")
+		return
+	}
+
+	urlChanges := CanonicalizeProject(project)
+	if len(urlChanges) > 0 {
+		doc = mergePatch(doc, canonicalURLPatch(project, urlChanges))
+	}
+
+	if problems := s.validator.Validate(project); len(problems) > 0 {
+		writeValidationErrorResponse(w, problems)
+		return
+	}
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error marshalling YAML: %v", err))
+		return
+	}
+
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error writing file: %v", err))
+		return
+	}
+	s.refreshIndex(name)
+
+	latestFile := fmt.Sprintf("%s.yaml", name)
+	s.recordFile(latestFile, actionUpdated)
+
+	// Stage only the edited project file
+	if err := s.stageFiles(projectRelPath(name)); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Error staging changes: %v", err))
+		return
+	}
+
+	log.Printf("Updated project %s", name)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{
+		Message:     "Project updated and changes staged",
+		LatestFile:  latestFile,
+		StagedFiles: s.session.StagedFiles(),
+		URLChanges:  urlChanges,
+	})
+}
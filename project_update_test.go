@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const testProjectYAML = `version: 7
+name: bebop
+display_name: Bebop
+x_internal: keep me
+social:
+  discord:
+  - url: https://discord.gg/bebop
+websites:
+- url: https://bebop.xyz
+`
+
+// parseTestDocument decodes YAML into an ordered document
+func parseTestDocument(t *testing.T, data string) yaml.MapSlice {
+	t.Helper()
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// marshalTestDocument encodes an ordered document as YAML
+func marshalTestDocument(t *testing.T, doc yaml.MapSlice) string {
+	t.Helper()
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMergePatchDocument(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{
+			"null deletes a field",
+			`{"websites": null, "x_internal": null}`,
+			"version: 7\nname: bebop\ndisplay_name: Bebop\nsocial:\n  discord:\n  - url: https://discord.gg/bebop\n",
+		},
+		{
+			"json key alias",
+			`{"displayName": "Bebop DEX"}`,
+			strings.Replace(testProjectYAML, "display_name: Bebop\n", "display_name: Bebop DEX\n", 1),
+		},
+		{
+			"yaml key",
+			`{"display_name": "Bebop DEX"}`,
+			strings.Replace(testProjectYAML, "display_name: Bebop\n", "display_name: Bebop DEX\n", 1),
+		},
+		{
+			"nested social merges",
+			`{"social": {"twitter": [{"url": "https://x.com/bebop_dex"}]}}`,
+			strings.Replace(testProjectYAML, "  - url: https://discord.gg/bebop\n", "  - url: https://discord.gg/bebop\n  twitter:\n  - url: https://x.com/bebop_dex\n", 1),
+		},
+		{
+			"nested null deletes",
+			`{"social": {"discord": null}}`,
+			strings.Replace(testProjectYAML, "social:\n  discord:\n  - url: https://discord.gg/bebop\n", "social: {}\n", 1),
+		},
+		{
+			"arrays are replaced",
+			`{"websites": [{"url": "https://bebop.exchange"}]}`,
+			strings.Replace(testProjectYAML, "https://bebop.xyz", "https://bebop.exchange", 1),
+		},
+		{
+			"new keys are appended in sorted order",
+			`{"openCollective": [{"url": "https://opencollective.com/bebop"}], "description": "DEX", "github": [{"url": "https://github.com/bebop-dex"}]}`,
+			testProjectYAML + "description: DEX\ngithub:\n- url: https://github.com/bebop-dex\nopen_collective:\n- url: https://opencollective.com/bebop\n",
+		},
+		{
+			"new nested objects",
+			`{"x_extra": {"b": 1, "a": {"c": 2.5}}}`,
+			testProjectYAML + "x_extra:\n  a:\n    c: 2.5\n  b: 1\n",
+		},
+		{
+			"floats become ints",
+			`{"version": 7.0, "x_internal": [1, 2.0, 3.25]}`,
+			strings.Replace(testProjectYAML, "x_internal: keep me\n", "x_internal:\n- 1\n- 2\n- 3.25\n", 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatal(err)
+			}
+
+			got := marshalTestDocument(t, mergePatchDocument(parseTestDocument(t, testProjectYAML), patch))
+			if got != tt.want {
+				t.Errorf("merged document =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceDocument(t *testing.T) {
+	replacement, err := projectToDocument(&Project{
+		Version:     ProjectSchemaVersion,
+		Name:        "bebop",
+		DisplayName: "Bebop DEX",
+		Github:      []URL{{Url: "https://github.com/bebop-dex"}},
+		Websites:    []URL{{Url: "https://bebop.exchange"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := marshalTestDocument(t, replaceDocument(parseTestDocument(t, testProjectYAML), replacement))
+	want := "version: 7\nname: bebop\ndisplay_name: Bebop DEX\nx_internal: keep me\nwebsites:\n- url: https://bebop.exchange\ngithub:\n- url: https://github.com/bebop-dex\n"
+	if got != want {
+		t.Errorf("replaced document =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestYAMLValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  interface{}
+	}{
+		{float64(7), int64(7)},
+		{float64(-3), int64(-3)},
+		{2.5, 2.5},
+		{float64(1 << 60), float64(1 << 60)},
+		{"7", "7"},
+		{true, true},
+		{[]interface{}{float64(1), "a"}, []interface{}{int64(1), "a"}},
+		{map[string]interface{}{"b": float64(2), "a": nil}, yaml.MapSlice{{Key: "b", Value: int64(2)}}},
+	}
+
+	for _, tt := range tests {
+		if got := yamlValue(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("yamlValue(%#v) = %#v, want %#v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateProjectHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		query  string
+		body   string
+		status int
+		want   string // Project file after the request
+	}{
+		{
+			"patch",
+			http.MethodPatch, "name=bebop",
+			`{"description": "A DEX", "social": {"discord": null}}`,
+			http.StatusOK,
+			"version: 7\nname: bebop\ndisplay_name: Bebop\nx_internal: keep me\nsocial: {}\nwebsites:\n- url: https://bebop.xyz\ndescription: A DEX\n",
+		},
+		{
+			"put keeps unknown keys",
+			http.MethodPut, "name=bebop",
+			`{"displayName": "Bebop DEX", "websites": [{"url": "https://www.bebop.exchange/"}]}`,
+			http.StatusOK,
+			"version: 7\nname: bebop\ndisplay_name: Bebop DEX\nx_internal: keep me\nwebsites:\n- url: https://bebop.exchange\n",
+		},
+		{
+			"patch cannot rename",
+			http.MethodPatch, "name=bebop",
+			`{"name": "bebop-dex"}`,
+			http.StatusBadRequest,
+			testProjectYAML,
+		},
+		{
+			"put cannot rename",
+			http.MethodPut, "name=bebop",
+			`{"name": "bebop-dex", "displayName": "Bebop"}`,
+			http.StatusBadRequest,
+			testProjectYAML,
+		},
+		{
+			"invalid result",
+			http.MethodPatch, "name=bebop",
+			`{"display_name": null}`,
+			http.StatusUnprocessableEntity,
+			testProjectYAML,
+		},
+		{"missing project", http.MethodPatch, "name=gamma", `{}`, http.StatusNotFound, testProjectYAML},
+		{"invalid name", http.MethodPatch, "name=../bebop", `{}`, http.StatusBadRequest, testProjectYAML},
+		{"method", http.MethodPost, "name=bebop", `{}`, http.StatusMethodNotAllowed, testProjectYAML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, false)
+			path := filepath.Join(s.config.DirectoryPath, projectRelPath("bebop"))
+			writeTestFile(t, s.config.DirectoryPath, projectRelPath("bebop"), testProjectYAML)
+
+			recorder := httptest.NewRecorder()
+			s.updateProjectHandler(recorder, httptest.NewRequest(tt.method, "/updateProject?"+tt.query, strings.NewReader(tt.body)))
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d (%s), want %d", recorder.Code, recorder.Body.String(), tt.status)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("project file =\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// NewPullRequest is what is needed to open a pull request
+type NewPullRequest struct {
+	Title string `json:"title"`
+	Head  string `json:"head"` // "<fork owner>:<branch>"
+	Base  string `json:"base"`
+	Body  string `json:"body,omitempty"`
+	Draft bool   `json:"draft,omitempty"`
+}
+
+// PullRequest is an opened pull request
+type PullRequest struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+	Head   string `json:"head"`
+	Base   string `json:"base"`
+}
+
+// PullRequestClient opens pull requests on a code host. repo is "<owner>/<name>".
+type PullRequestClient interface {
+	CreatePullRequest(repo string, pr NewPullRequest) (*PullRequest, error)
+}
+
+// CreatePullRequest opens a pull request through the GitHub REST API
+func (gp *GitHubProvider) CreatePullRequest(repo string, pr NewPullRequest) (*PullRequest, error) {
+	var created struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+		Title   string `json:"title"`
+	}
+	if err := gp.do(http.MethodPost, "/repos/"+repo+"/pulls", pr, &created); err != nil {
+		return nil, err
+	}
+
+	return &PullRequest{
+		Number: created.Number,
+		URL:    created.HTMLURL,
+		Title:  created.Title,
+		Head:   pr.Head,
+		Base:   pr.Base,
+	}, nil
+}
+
+// newPullRequestClient opens pull requests through the configured GitHub API
+func newPullRequestClient(cfg Config) PullRequestClient {
+	return NewGitHubProvider(cfg.GitHubAPIURL, cfg.GitHubToken)
+}
+
+// Remote URLs of GitHub repositories over HTTPS, SSH or the scp-like syntax
+var githubRemotePattern = regexp.MustCompile(`^(?:https://|ssh://git@|git@)github\.com[/:]([A-Za-z0-9-]+)/([A-Za-z0-9._-]+?)(?:\.git)?/?$`)
+
+// GitHubRepoFromRemote extracts "<owner>/<name>" from a GitHub remote URL
+func GitHubRepoFromRemote(remoteURL string) (string, error) {
+	match := githubRemotePattern.FindStringSubmatch(strings.TrimSpace(remoteURL))
+	if match == nil {
+		return "", fmt.Errorf("%q is not a GitHub repository URL", remoteURL)
+	}
+	return match[1] + "/" + match[2], nil
+}
+
+// remoteRepo returns the GitHub repository a git remote points at
+func (s *Server) remoteRepo(remote string) (string, error) {
+	// Read the configured URL as is, without insteadOf rewrites
+	result, err := s.runGit("config", "--get", "remote."+remote+".url")
+	if err != nil {
+		return "", fmt.Errorf("remote %q is not configured", remote)
+	}
+	return GitHubRepoFromRemote(result.Stdout)
+}
+
+// PullRequestRequest controls the pull request opened for the checked out commits
+type PullRequestRequest struct {
+	Branch string `json:"branch,omitempty"` // Feature branch to create; generated when empty
+	Title  string `json:"title,omitempty"`  // Generated from the commits when empty
+	Body   string `json:"body,omitempty"`   // Generated from the commits when empty
+	Draft  bool   `json:"draft,omitempty"`
+}
+
+// PullRequestResponse reports the opened pull request
+type PullRequestResponse struct {
+	Message     string       `json:"message"`
+	PullRequest *PullRequest `json:"pullRequest"`
+	Branch      string       `json:"branch"`
+	Error       string       `json:"error,omitempty"`
+	Git         []*GitResult `json:"git"`
+}
+
+// pullRequestMessage summarizes the commits that are ahead of upstream with the
+// same generator used for session commits. Without an upstream ref, the last
+// commit's message is used.
+func (s *Server) pullRequestMessage() (string, string, error) {
+	upstream := s.config.UpstreamRemote + "/" + s.config.UpstreamBranch
+
+	if _, err := s.runGit("rev-parse", "--verify", "--quiet", upstream); err != nil {
+		last, err := s.runGit("log", "-1", "--format=%s%n%n%b")
+		if err != nil {
+			return "", "", fmt.Errorf("error reading last commit: %v", err)
+		}
+		subject, body, _ := strings.Cut(strings.TrimSpace(last.Stdout), "\n\n")
+		return subject, strings.TrimSpace(body), nil
+	}
+
+	ahead, err := s.runGit("rev-list", "--count", upstream+"..HEAD")
+	if err != nil {
+		return "", "", fmt.Errorf("error comparing with %s: %v", upstream, err)
+	}
+	if strings.TrimSpace(ahead.Stdout) == "0" {
+		return "", "", fmt.Errorf("no commits ahead of %s to open a pull request for", upstream)
+	}
+
+	diff, err := s.runGit("diff", "--name-status", "-z", upstream+"...HEAD")
+	if err != nil {
+		return "", "", fmt.Errorf("error listing changes: %v", err)
+	}
+
+	changes := parseNameStatus(diff.Stdout)
+	if len(changes) == 0 {
+		return "", "", fmt.Errorf("commits ahead of %s change no files", upstream)
+	}
+
+	subject, body := BuildCommitMessage(changes)
+	return subject, body, nil
+}
+
+// createPullRequestHandler creates a feature branch at the checked out commit, pushes
+// it to the origin remote and opens a pull request against the upstream repository
+func (s *Server) createPullRequestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	var req PullRequestRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %v", err))
+			return
+		}
+	}
+
+	if s.config.GitHubToken == "" {
+		writeErrorResponse(w, http.StatusPreconditionFailed, "A GitHub token is required to open pull requests (set github_token)")
+		return
+	}
+
+	branch := req.Branch
+	if branch == "" {
+		branch = "oss-adder/" + time.Now().Format("20060102-150405")
+	}
+	if !validBranchName(branch) {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("invalid branch name: %q", branch))
+		return
+	}
+
+	upstreamRepo, err := s.remoteRepo(s.config.UpstreamRemote)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error resolving upstream repository: %v", err))
+		return
+	}
+	forkRepo, err := s.remoteRepo(s.config.OriginRemote)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Error resolving fork repository: %v", err))
+		return
+	}
+
+	title, body, err := s.pullRequestMessage()
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Title != "" {
+		title = req.Title
+	}
+	if req.Body != "" {
+		body = req.Body
+	}
+
+	response := PullRequestResponse{Branch: branch, Git: []*GitResult{}}
+	created := false
+	// deleteBranch removes the branch created here when a later step fails, so the
+	// request can be retried with the same name
+	deleteBranch := func() {
+		if !created {
+			return
+		}
+		if result, err := s.runGit("branch", "-D", branch); err != nil {
+			log.Printf("Warning: Failed to delete branch %s: %v\nStderr: %s", branch, err, result.Stderr)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"branch", branch, "HEAD"},
+		{"push", s.config.OriginRemote, "refs/heads/" + branch},
+	} {
+		result, err := s.runGit(args...)
+		response.Git = append(response.Git, result)
+		if err != nil {
+			log.Printf("Git %s failed: %v\nStderr: %s", args[0], err, result.Stderr)
+			deleteBranch()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			response.Error = fmt.Sprintf("Error running git %s: %v", args[0], err)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		created = true
+	}
+
+	owner, _, _ := strings.Cut(forkRepo, "/")
+	pr, err := s.pulls.CreatePullRequest(upstreamRepo, NewPullRequest{
+		Title: title,
+		Head:  owner + ":" + branch,
+		Base:  s.config.UpstreamBranch,
+		Body:  body,
+		Draft: req.Draft,
+	})
+	var apiErr *GitHubAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity {
+		deleteBranch()
+		writeErrorResponse(w, http.StatusConflict, fmt.Sprintf("Error opening pull request: %v", err))
+		return
+	}
+	if err != nil {
+		deleteBranch()
+		writeErrorResponse(w, http.StatusBadGateway, fmt.Sprintf("Error opening pull request: %v", err))
+		return
+	}
+
+	s.recordAction(pr.URL, actionPullRequestOpened)
+
+	response.Message = fmt.Sprintf("Opened pull request #%d", pr.Number)
+	response.PullRequest = pr
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
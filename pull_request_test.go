@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testUpstreamRepo = "opensource-observer/oss-directory"
+	testUpstreamURL  = "git@github.com:opensource-observer/oss-directory.git"
+	testForkURL      = "https://github.com/me/oss-directory.git"
+)
+
+// gitTest runs git in dir and fails the test on error
+func gitTest(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// writeTestFile writes a file of the checkout, creating its directory
+func writeTestFile(t *testing.T, dir, relPath, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pullRequestFixture is a checkout with GitHub remotes that resolve to local bare
+// repositories, one commit ahead of upstream, and a server talking to a fake GitHub
+type pullRequestFixture struct {
+	server   *Server
+	github   *FakeGitHub
+	checkout string
+	fork     string
+}
+
+func newPullRequestFixture(t *testing.T) *pullRequestFixture {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	upstream := filepath.Join(root, "upstream.git")
+	fork := filepath.Join(root, "fork.git")
+	checkout := filepath.Join(root, "checkout")
+	for _, dir := range []string{upstream, fork, checkout} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitTest(t, upstream, "init", "-q", "--bare")
+	gitTest(t, fork, "init", "-q", "--bare")
+
+	gitTest(t, checkout, "init", "-q")
+	gitTest(t, checkout, "remote", "add", "upstream", testUpstreamURL)
+	gitTest(t, checkout, "remote", "add", "origin", testForkURL)
+	gitTest(t, checkout, "config", "url."+upstream+".insteadOf", testUpstreamURL)
+	gitTest(t, checkout, "config", "url."+fork+".insteadOf", testForkURL)
+
+	writeTestFile(t, checkout, "data/projects/b/bebop-dex.yaml", "version: 7\nname: bebop-dex\ndisplay_name: Bebop\n")
+	gitTest(t, checkout, "add", "-A")
+	gitTest(t, checkout, "commit", "-q", "-m", "initial")
+	gitTest(t, checkout, "push", "-q", "upstream", "main")
+	gitTest(t, checkout, "push", "-q", "origin", "main")
+	gitTest(t, checkout, "fetch", "-q", "upstream")
+
+	writeTestFile(t, checkout, "data/projects/a/alpha.yaml", "version: 7\nname: alpha\ndisplay_name: Alpha\n")
+	writeTestFile(t, checkout, "data/projects/b/bebop-dex.yaml", "version: 7\nname: bebop-dex\ndisplay_name: Bebop DEX\n")
+	gitTest(t, checkout, "add", "-A")
+	gitTest(t, checkout, "commit", "-q", "-m", "add alpha")
+
+	cfg := DefaultConfig()
+	cfg.DirectoryPath = checkout
+	cfg.StateDir = t.TempDir()
+	cfg.GitHubToken = "test-token"
+
+	server, err := NewServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	github := StartFakeGitHub()
+	t.Cleanup(github.Close)
+	server.pulls = NewGitHubProvider(github.URL, cfg.GitHubToken)
+
+	return &pullRequestFixture{server: server, github: github, checkout: checkout, fork: fork}
+}
+
+// createPullRequest calls the handler with a JSON body, or none when body is nil
+func (f *pullRequestFixture) createPullRequest(t *testing.T, body interface{}) (int, PullRequestResponse) {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body == nil {
+		reader = bytes.NewReader(nil)
+	} else {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	recorder := httptest.NewRecorder()
+	f.server.createPullRequestHandler(recorder, httptest.NewRequest(http.MethodPost, "/createPullRequest", reader))
+
+	var response PullRequestResponse
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	if response.Error == "" {
+		var errorBody Response
+		json.Unmarshal(recorder.Body.Bytes(), &errorBody)
+		response.Error = errorBody.Error
+	}
+	return recorder.Code, response
+}
+
+func TestCreatePullRequestGeneratesMessage(t *testing.T) {
+	f := newPullRequestFixture(t)
+
+	status, response := f.createPullRequest(t, nil)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", status, response.Error)
+	}
+
+	if !strings.HasPrefix(response.Branch, "oss-adder/") {
+		t.Errorf("branch = %q, want the default oss-adder/<timestamp>", response.Branch)
+	}
+	if response.PullRequest == nil || response.PullRequest.URL != "https://github.com/"+testUpstreamRepo+"/pull/1" {
+		t.Fatalf("pull request = %+v", response.PullRequest)
+	}
+
+	requests := f.github.Requests(testUpstreamRepo)
+	if len(requests) != 1 {
+		t.Fatalf("got %d pull requests, want 1", len(requests))
+	}
+	pr := requests[0]
+	if pr.Title != "feat: add 1 project and update 1 project (alpha, bebop-dex)" {
+		t.Errorf("title = %q", pr.Title)
+	}
+	if pr.Body != "- added data/projects/a/alpha.yaml\n- updated data/projects/b/bebop-dex.yaml" {
+		t.Errorf("body = %q", pr.Body)
+	}
+	if pr.Head != "me:"+response.Branch || pr.Base != "main" || pr.Draft {
+		t.Errorf("head %q, base %q, draft %v; want me:%s, main, false", pr.Head, pr.Base, pr.Draft, response.Branch)
+	}
+
+	// The branch was pushed to the fork at the checked out commit
+	head := gitTest(t, f.checkout, "rev-parse", "HEAD")
+	if pushed := gitTest(t, f.fork, "rev-parse", "refs/heads/"+response.Branch); pushed != head {
+		t.Errorf("pushed branch is at %s, want %s", pushed, head)
+	}
+}
+
+func TestCreatePullRequestOverrides(t *testing.T) {
+	f := newPullRequestFixture(t)
+
+	status, response := f.createPullRequest(t, PullRequestRequest{
+		Branch: "add-alpha",
+		Title:  "Add Alpha",
+		Body:   "Adds the Alpha project.",
+		Draft:  true,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", status, response.Error)
+	}
+
+	pr := f.github.Requests(testUpstreamRepo)[0]
+	if pr.Title != "Add Alpha" || pr.Body != "Adds the Alpha project." || !pr.Draft || pr.Head != "me:add-alpha" {
+		t.Errorf("pull request = %+v", pr)
+	}
+}
+
+func TestCreatePullRequestErrors(t *testing.T) {
+	t.Run("invalid branch", func(t *testing.T) {
+		f := newPullRequestFixture(t)
+		if status, _ := f.createPullRequest(t, PullRequestRequest{Branch: "-delete"}); status != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", status)
+		}
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		f := newPullRequestFixture(t)
+		f.server.config.GitHubToken = ""
+		if status, _ := f.createPullRequest(t, nil); status != http.StatusPreconditionFailed {
+			t.Errorf("status = %d, want 412", status)
+		}
+	})
+
+	t.Run("nothing ahead of upstream", func(t *testing.T) {
+		f := newPullRequestFixture(t)
+		gitTest(t, f.checkout, "reset", "-q", "--hard", "upstream/main")
+		status, response := f.createPullRequest(t, nil)
+		if status != http.StatusBadRequest || !strings.Contains(response.Error, "no commits ahead") {
+			t.Errorf("status = %d (%s), want 400 for no commits ahead", status, response.Error)
+		}
+	})
+
+	t.Run("pull request already exists", func(t *testing.T) {
+		f := newPullRequestFixture(t)
+		// Someone else already opened one for the same head through the API
+		if _, err := NewGitHubProvider(f.github.URL, "other-token").CreatePullRequest(testUpstreamRepo, NewPullRequest{
+			Title: "Add Alpha", Head: "me:add-alpha", Base: "main",
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		status, response := f.createPullRequest(t, PullRequestRequest{Branch: "add-alpha"})
+		if status != http.StatusConflict || !strings.Contains(response.Error, "already exists") {
+			t.Errorf("status = %d (%s), want 409", status, response.Error)
+		}
+		if branches := gitTest(t, f.checkout, "branch", "--list", "add-alpha"); branches != "" {
+			t.Errorf("local branch was left behind: %q", branches)
+		}
+	})
+
+	t.Run("push fails", func(t *testing.T) {
+		f := newPullRequestFixture(t)
+		hook := filepath.Join(f.fork, "hooks", "pre-receive")
+		writeTestFile(t, f.fork, "hooks/pre-receive", "#!/bin/sh\nexit 1\n")
+		if err := os.Chmod(hook, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		status, response := f.createPullRequest(t, PullRequestRequest{Branch: "add-alpha"})
+		if status != http.StatusInternalServerError || !strings.Contains(response.Error, "git push") {
+			t.Fatalf("status = %d (%s), want 500 for the failed push", status, response.Error)
+		}
+		if branches := gitTest(t, f.checkout, "branch", "--list", "add-alpha"); branches != "" {
+			t.Errorf("local branch was left behind: %q", branches)
+		}
+		if len(f.github.PullRequests(testUpstreamRepo)) != 0 {
+			t.Error("a pull request was opened")
+		}
+
+		// Retrying with the same name works once the fork accepts the push
+		if err := os.Remove(hook); err != nil {
+			t.Fatal(err)
+		}
+		if status, response := f.createPullRequest(t, PullRequestRequest{Branch: "add-alpha"}); status != http.StatusOK {
+			t.Errorf("retry status = %d (%s), want 200", status, response.Error)
+		}
+	})
+
+	t.Run("existing local branch is kept", func(t *testing.T) {
+		f := newPullRequestFixture(t)
+		gitTest(t, f.checkout, "branch", "add-alpha", "HEAD~1")
+
+		if status, _ := f.createPullRequest(t, PullRequestRequest{Branch: "add-alpha"}); status != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", status)
+		}
+		if branches := gitTest(t, f.checkout, "branch", "--list", "add-alpha"); branches == "" {
+			t.Error("a branch the handler did not create was deleted")
+		}
+	})
+
+	t.Run("rejected by the API", func(t *testing.T) {
+		f := newPullRequestFixture(t)
+		f.server.pulls = NewGitHubProvider(f.github.URL, "")
+		status, response := f.createPullRequest(t, nil)
+		if status != http.StatusBadGateway || !strings.Contains(response.Error, "401") {
+			t.Errorf("status = %d (%s), want 502 for a 401 from the API", status, response.Error)
+		}
+	})
+
+	t.Run("method", func(t *testing.T) {
+		f := newPullRequestFixture(t)
+		recorder := httptest.NewRecorder()
+		f.server.createPullRequestHandler(recorder, httptest.NewRequest(http.MethodGet, "/createPullRequest", nil))
+		if recorder.Code != http.StatusMethodNotAllowed {
+			t.Errorf("status = %d, want 405", recorder.Code)
+		}
+	})
+}
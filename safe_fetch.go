@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Limits applied to every outbound fetch of a user-supplied URL
+const (
+	fetchTimeout      = 10 * time.Second
+	fetchMaxRedirects = 5
+)
+
+// FetchOptions relaxes the outbound fetch policy. It is only honoured in dev mode.
+type FetchOptions struct {
+	// Insecure skips TLS certificate verification and allows private, loopback and
+	// link-local addresses, for testing against local development sites
+	Insecure bool
+}
+
+// Content types accepted for each kind of fetched resource
+var (
+	htmlContentTypes     = []string{"text/html", "application/xhtml+xml"}
+	manifestContentTypes = []string{"application/manifest+json", "application/json", "text/plain"}
+	iconContentTypes     = []string{"image/*", "application/octet-stream", "text/plain", "text/xml", "application/xml"}
+)
+
+// Address ranges that are never fetched besides the ones covered by net.IP helpers
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "This" network
+	"100.64.0.0/10", // Carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // Benchmarking
+	"240.0.0.0/4",   // Reserved
+	"64:ff9b::/96",  // NAT64, may embed private IPv4 addresses
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isBlockedIP reports whether ip is an internal address that must not be fetched
+func isBlockedIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockInternalAddresses is a dialer control function that refuses connections to
+// internal addresses. It runs after DNS resolution, so hostnames that resolve to
+// private ranges are caught as well.
+func blockInternalAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("refusing to connect to unresolved address %s", host)
+	}
+	if isBlockedIP(ip) {
+		return fmt.Errorf("refusing to connect to internal address %s", ip)
+	}
+	return nil
+}
+
+// NewFetchClient creates the HTTP client used for all user-supplied URLs. Certificates
+// are verified, internal addresses are blocked and redirects are limited unless opts
+// relaxes the policy.
+func NewFetchClient(opts FetchOptions) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: fetchTimeout,
+	}
+	if !opts.Insecure {
+		dialer.Control = blockInternalAddresses
+	}
+
+	tr := &http.Transport{
+		// Proxies would connect on our behalf and bypass the address check
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: opts.Insecure},
+		TLSHandshakeTimeout:   fetchTimeout,
+		ResponseHeaderTimeout: fetchTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &http.Client{
+		Transport: tr,
+		Timeout:   fetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= fetchMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", fetchMaxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("refusing to follow redirect to %s URL", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// checkContentType verifies that a response declares one of the allowed media types.
+// A missing Content-Type is accepted because the body is sniffed afterwards anyway.
+func checkContentType(resp *http.Response, allowed []string) error {
+	header := resp.Header.Get("Content-Type")
+	if header == "" {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q", header)
+	}
+
+	for _, pattern := range allowed {
+		if mediaType == pattern {
+			return nil
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected content type %s", mediaType)
+}
+
+// errResponseTooLarge is returned when a response body exceeds its size cap
+var errResponseTooLarge = errors.New("response is too large")
+
+// readLimited reads at most limit bytes from r and fails if there is more
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w (over %d bytes)", errResponseTooLarge, limit)
+	}
+	return data, nil
+}
+
+// fetchOptions reads the fetch policy requested with insecure=true, which is
+// refused unless the server runs in dev mode
+func (s *Server) fetchOptions(r *http.Request) (FetchOptions, error) {
+	if r.URL.Query().Get("insecure") != "true" {
+		return FetchOptions{}, nil
+	}
+	if !s.config.DevMode {
+		return FetchOptions{}, errors.New("insecure fetching is only allowed when the server runs in dev mode")
+	}
+	return FetchOptions{Insecure: true}, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestIsBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"127.8.9.10", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true}, // Cloud metadata
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"100.64.0.1", true},
+		{"192.0.0.8", true},
+		{"198.18.0.1", true},
+		{"224.0.0.1", true},
+		{"240.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::1", true},
+		{"::", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"ff02::1", true},
+		{"::ffff:127.0.0.1", true}, // IPv4-mapped loopback
+		{"::ffff:10.0.0.1", true},
+		{"64:ff9b::a00:1", true}, // NAT64 of 10.0.0.1
+		{"8.8.8.8", false},
+		{"1.1.1.1", false},
+		{"172.32.0.1", false},
+		{"100.128.0.1", false},
+		{"2606:4700:4700::1111", false},
+		{"::ffff:8.8.8.8", false},
+	}
+
+	for _, tt := range tests {
+		if got := isBlockedIP(net.ParseIP(tt.ip)); got != tt.blocked {
+			t.Errorf("isBlockedIP(%s) = %v, want %v", tt.ip, got, tt.blocked)
+		}
+	}
+}
+
+func TestBlockInternalAddresses(t *testing.T) {
+	tests := []struct {
+		address string
+		err     string
+	}{
+		{"93.184.216.34:443", ""},
+		{"[2606:4700:4700::1111]:443", ""},
+		{"127.0.0.1:80", "refusing to connect to internal address 127.0.0.1"},
+		{"[::1]:80", "refusing to connect to internal address ::1"},
+		{"localhost:80", "refusing to connect to unresolved address localhost"},
+		{"127.0.0.1", "missing port"},
+	}
+
+	for _, tt := range tests {
+		err := blockInternalAddresses("tcp", tt.address, nil)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("blockInternalAddresses(%s) error = %v", tt.address, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("blockInternalAddresses(%s) error = %v, want %q", tt.address, err, tt.err)
+		}
+	}
+}
+
+// newRedirectSite serves /hop/N, which redirects to /hop/N-1 until /hop/0 answers
+func newRedirectSite(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hop/{n}", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.PathValue("n"))
+		if n == 0 {
+			w.Write([]byte("ok"))
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+	})
+	mux.HandleFunc("/ftp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://example.org/file", http.StatusFound)
+	})
+
+	site := httptest.NewServer(mux)
+	t.Cleanup(site.Close)
+	return site
+}
+
+func TestNewFetchClient(t *testing.T) {
+	site := newRedirectSite(t)
+
+	t.Run("internal addresses are refused", func(t *testing.T) {
+		_, err := NewFetchClient(FetchOptions{}).Get(site.URL + "/hop/0")
+		if err == nil || !strings.Contains(err.Error(), "refusing to connect to internal address") {
+			t.Errorf("Get() error = %v, want the loopback address refused", err)
+		}
+	})
+
+	t.Run("insecure allows internal addresses", func(t *testing.T) {
+		resp, err := NewFetchClient(FetchOptions{Insecure: true}).Get(site.URL + "/hop/0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	})
+
+	t.Run("redirect limit", func(t *testing.T) {
+		client := NewFetchClient(FetchOptions{Insecure: true})
+
+		// Like net/http's default policy, the limit counts requests made so far
+		resp, err := client.Get(fmt.Sprintf("%s/hop/%d", site.URL, fetchMaxRedirects-1))
+		if err != nil {
+			t.Fatalf("%d redirects: %v", fetchMaxRedirects-1, err)
+		}
+		resp.Body.Close()
+
+		_, err = client.Get(fmt.Sprintf("%s/hop/%d", site.URL, fetchMaxRedirects))
+		if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("stopped after %d redirects", fetchMaxRedirects)) {
+			t.Errorf("%d redirects error = %v, want the limit to stop it", fetchMaxRedirects, err)
+		}
+	})
+
+	t.Run("redirects to other schemes", func(t *testing.T) {
+		_, err := NewFetchClient(FetchOptions{Insecure: true}).Get(site.URL + "/ftp")
+		if err == nil || !strings.Contains(err.Error(), "refusing to follow redirect to ftp URL") {
+			t.Errorf("Get() error = %v", err)
+		}
+	})
+
+	t.Run("certificates are verified", func(t *testing.T) {
+		tlsSite := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer tlsSite.Close()
+
+		// Only the secure client verifies, but it refuses loopback before the handshake,
+		// so check the transport configuration instead
+		transport := NewFetchClient(FetchOptions{}).Transport.(*http.Transport)
+		if transport.TLSClientConfig.InsecureSkipVerify || transport.Proxy != nil {
+			t.Error("the secure client skips verification or uses a proxy")
+		}
+		resp, err := NewFetchClient(FetchOptions{Insecure: true}).Get(tlsSite.URL)
+		if err != nil {
+			t.Fatalf("insecure client error = %v", err)
+		}
+		resp.Body.Close()
+	})
+}
+
+func TestCheckContentType(t *testing.T) {
+	tests := []struct {
+		header  string
+		allowed []string
+		ok      bool
+	}{
+		{"", htmlContentTypes, true},
+		{"text/html; charset=utf-8", htmlContentTypes, true},
+		{"application/xhtml+xml", htmlContentTypes, true},
+		{"application/json", htmlContentTypes, false},
+		{"image/png", iconContentTypes, true},
+		{"image/svg+xml", iconContentTypes, true},
+		{"imagery/png", iconContentTypes, false},
+		{"text/html", iconContentTypes, false},
+		{"application/manifest+json", manifestContentTypes, true},
+		{"text/html;;", htmlContentTypes, false},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Content-Type", tt.header)
+		}
+		if err := checkContentType(resp, tt.allowed); (err == nil) != tt.ok {
+			t.Errorf("checkContentType(%q) error = %v, want ok %v", tt.header, err, tt.ok)
+		}
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	if data, err := readLimited(strings.NewReader("12345"), 5); err != nil || string(data) != "12345" {
+		t.Errorf("readLimited(5 bytes, 5) = %q, %v", data, err)
+	}
+	if _, err := readLimited(strings.NewReader("123456"), 5); !errors.Is(err, errResponseTooLarge) {
+		t.Errorf("readLimited(6 bytes, 5) error = %v, want errResponseTooLarge", err)
+	}
+}
+
+func TestFetchOptions(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fetchFavicon?insecure=true", nil)
+
+	if _, err := newTestServer(t, false).fetchOptions(request); err == nil {
+		t.Error("insecure fetching was allowed outside dev mode")
+	}
+	if opts, err := newTestServer(t, true).fetchOptions(request); err != nil || !opts.Insecure {
+		t.Errorf("fetchOptions() in dev mode = %+v, %v", opts, err)
+	}
+	if opts, err := newTestServer(t, false).fetchOptions(httptest.NewRequest(http.MethodGet, "/fetchFavicon", nil)); err != nil || opts.Insecure {
+		t.Errorf("fetchOptions() = %+v, %v; want the secure default", opts, err)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Actions recorded in the session history
+const (
+	actionCreated        = "created"
+	actionUpdated        = "updated"
+	actionFaviconSaved   = "favicon_saved"
+	actionFaviconRemoved = "favicon_removed"
+	actionRenamed        = "renamed"
+
+	actionCollectionCreated = "collection_created"
+	actionCollectionUpdated = "collection_updated"
+	actionPullRequestOpened = "pull_request_opened"
+)
+
+// WorkItem records one change the tool made in the checkout
+type WorkItem struct {
+	File      string    `json:"file"`
+	Action    string    `json:"action"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SessionState is the session data that survives server restarts
+type SessionState struct {
+	LatestFile  string     `json:"latestFile"`
+	AddedFiles  []string   `json:"addedFiles"`
+	StagedFiles []string   `json:"stagedFiles"`
+	History     []WorkItem `json:"history"`
+
+	// TouchedPaths are the checkout paths the tool wrote or deleted and has not
+	// seen committed yet; directories cover everything below them
+	TouchedPaths []string `json:"touchedPaths"`
+}
+
+// HistoryDay summarizes the work recorded on one calendar day
+type HistoryDay struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+// SessionStore keeps the session state in memory and mirrors it to a JSON file
+type SessionStore struct {
+	path string
+
+	mutex sync.Mutex
+	state SessionState
+}
+
+// sessionFileName is the name of the state file inside the state directory
+const sessionFileName = "session.json"
+
+// historyDayFormat is the layout of the day parameter of the history endpoints
+const historyDayFormat = "2006-01-02"
+
+// OpenSessionStore loads the session state from stateDir, starting empty if none exists yet
+func OpenSessionStore(stateDir string) (*SessionStore, error) {
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating state directory: %v", err)
+	}
+
+	store := &SessionStore{path: filepath.Join(stateDir, sessionFileName)}
+
+	data, err := os.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading session state: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &store.state); err != nil {
+		return nil, fmt.Errorf("error parsing session state %s: %v", store.path, err)
+	}
+
+	return store, nil
+}
+
+// save writes the state to disk atomically. The caller must hold the mutex.
+func (st *SessionStore) save() error {
+	data, err := json.MarshalIndent(st.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding session state: %v", err)
+	}
+
+	tmpPath := st.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("error writing session state: %v", err)
+	}
+	if err := os.Rename(tmpPath, st.path); err != nil {
+		return fmt.Errorf("error replacing session state: %v", err)
+	}
+
+	return nil
+}
+
+// RecordFile marks file as the latest file of the session and appends a history entry
+func (st *SessionStore) RecordFile(file, action string) error {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	st.state.LatestFile = file
+	if !slices.Contains(st.state.AddedFiles, file) {
+		st.state.AddedFiles = append(st.state.AddedFiles, file)
+	}
+	st.state.History = append(st.state.History, WorkItem{
+		File:      file,
+		Action:    action,
+		Timestamp: time.Now(),
+	})
+
+	return st.save()
+}
+
+// RecordAction appends a history entry without changing the session's file list
+func (st *SessionStore) RecordAction(file, action string) error {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	st.state.History = append(st.state.History, WorkItem{
+		File:      file,
+		Action:    action,
+		Timestamp: time.Now(),
+	})
+
+	return st.save()
+}
+
+// SetStagedFiles replaces the list of files currently staged in the checkout
+func (st *SessionStore) SetStagedFiles(files []string) error {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	st.state.StagedFiles = files
+	return st.save()
+}
+
+// TouchPaths records checkout paths as changed by the tool
+func (st *SessionStore) TouchPaths(paths ...string) error {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	for _, path := range paths {
+		if !slices.Contains(st.state.TouchedPaths, path) {
+			st.state.TouchedPaths = append(st.state.TouchedPaths, path)
+		}
+	}
+	sort.Strings(st.state.TouchedPaths)
+	return st.save()
+}
+
+// SetTouchedPaths replaces the checkout paths recorded as changed by the tool
+func (st *SessionStore) SetTouchedPaths(paths []string) error {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	st.state.TouchedPaths = paths
+	return st.save()
+}
+
+// TouchedPaths returns a copy of the checkout paths changed by the tool
+func (st *SessionStore) TouchedPaths() []string {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	return append([]string{}, st.state.TouchedPaths...)
+}
+
+// ResetFiles clears the session's file list; the history is kept
+func (st *SessionStore) ResetFiles() error {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	st.state.AddedFiles = []string{}
+	st.state.LatestFile = ""
+	return st.save()
+}
+
+// LatestFile returns the file most recently created or edited
+func (st *SessionStore) LatestFile() string {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	return st.state.LatestFile
+}
+
+// AddedFiles returns a copy of the files created or edited this session
+func (st *SessionStore) AddedFiles() []string {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	return append([]string{}, st.state.AddedFiles...)
+}
+
+// StagedFiles returns a copy of the files last seen staged in the checkout
+func (st *SessionStore) StagedFiles() []string {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	return append([]string{}, st.state.StagedFiles...)
+}
+
+// HistoryForDay returns the work items recorded on the given local calendar day
+func (st *SessionStore) HistoryForDay(day time.Time) []WorkItem {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	items := []WorkItem{}
+	for _, item := range st.state.History {
+		if item.Timestamp.Local().Format(historyDayFormat) == day.Format(historyDayFormat) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// HistoryDays returns every day with recorded work, most recent first
+func (st *SessionStore) HistoryDays() []HistoryDay {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	counts := make(map[string]int)
+	for _, item := range st.state.History {
+		counts[item.Timestamp.Local().Format(historyDayFormat)]++
+	}
+
+	days := make([]HistoryDay, 0, len(counts))
+	for day, count := range counts {
+		days = append(days, HistoryDay{Day: day, Count: count})
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Day > days[j].Day
+	})
+	return days
+}
+
+// recordFile records a session change, logging instead of failing the request
+func (s *Server) recordFile(file, action string) {
+	if err := s.session.RecordFile(file, action); err != nil {
+		log.Printf("Warning: Failed to persist session state: %v", err)
+	}
+}
+
+// recordAction records a history entry, logging instead of failing the request
+func (s *Server) recordAction(file, action string) {
+	if err := s.session.RecordAction(file, action); err != nil {
+		log.Printf("Warning: Failed to persist session state: %v", err)
+	}
+}
+
+// getHistoryDaysHandler lists the days that have recorded work
+func (s *Server) getHistoryDaysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	response := struct {
+		Days []HistoryDay `json:"days"`
+	}{
+		Days: s.session.HistoryDays(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getHistoryHandler returns the work recorded on the day given as YYYY-MM-DD (default today)
+func (s *Server) getHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Invalid request method")
+		return
+	}
+
+	setCorsHeaders(w)
+
+	day := time.Now()
+	if param := r.URL.Query().Get("day"); param != "" {
+		parsed, err := time.ParseInLocation(historyDayFormat, param, time.Local)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "day must be formatted as YYYY-MM-DD")
+			return
+		}
+		day = parsed
+	}
+
+	response := struct {
+		Day   string     `json:"day"`
+		Items []WorkItem `json:"items"`
+	}{
+		Day:   day.Format(historyDayFormat),
+		Items: s.session.HistoryForDay(day),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -8,9 +8,8 @@ import (
 )
 
 // TestFavicon is a test function for the favicon handler implementation
-func TestFavicon() {
+func TestFavicon(baseDir string) {
 	// Create a favicon handler with the test directory
-	baseDir := "/Users/ahoura/oss-directory" // Use the same base directory
 	handler := NewFaviconHandler(baseDir)
 
 	// Test URL to fetch
@@ -18,7 +17,7 @@ func TestFavicon() {
 	fmt.Println("Fetching favicon from:", testURL)
 
 	// Test fetching favicon
-	favicon, err := handler.FetchFavicon(testURL)
+	favicon, sourceURL, err := handler.FetchFavicon(testURL, FetchOptions{})
 	if err != nil {
 		log.Fatalf("Failed to fetch favicon: %v", err)
 	}
@@ -41,7 +40,7 @@ func TestFavicon() {
 	os.MkdirAll(logosDir, 0755)
 
 	// Test saving favicon
-	savePath, err := handler.SaveFavicon(testProject, favicon)
+	savePath, err := handler.SaveFavicon(testProject, favicon, sourceURL)
 	if err != nil {
 		log.Fatalf("Failed to save favicon: %v", err)
 	}
@@ -69,7 +68,7 @@ func TestFavicon() {
 	fmt.Println("All tests passed!")
 }
 
-func RunTest() {
+func RunTest(baseDir string) {
 	fmt.Println("Running favicon handler tests...")
-	TestFavicon()
+	TestFavicon(baseDir)
 }
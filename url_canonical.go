@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URLChange reports a project URL that was rewritten into its canonical form
+type URLChange struct {
+	Field     string `json:"field"`
+	Original  string `json:"original"`
+	Canonical string `json:"canonical,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// Query parameters that only track where a visitor came from
+var trackingParams = map[string]bool{
+	"ref":     true,
+	"ref_src": true,
+	"fbclid":  true,
+	"gclid":   true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"si":      true,
+}
+
+// Hosts whose profile URLs are fully identified by their path, so any query is dropped
+var pathOnlyHosts = map[string]bool{
+	"github.com": true,
+	"x.com":      true,
+	"t.me":       true,
+	"discord.gg": true,
+	"mirror.xyz": true,
+}
+
+// CanonicalizeProject rewrites every URL of a project into its canonical form,
+// drops URLs that became duplicates within a field, and reports each change
+func CanonicalizeProject(project *Project) []URLChange {
+	var changes []URLChange
+
+	for _, field := range projectURLFields(project) {
+		if len(field.urls) == 0 {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		kept := make([]URL, 0, len(*field.ref))
+		for i, u := range *field.ref {
+			path := fmt.Sprintf("%s[%d].url", field.name, i)
+			canonical := CanonicalizeURL(field.name, u.Url)
+
+			// Empty URLs are kept for validation to report
+			if canonical != "" && seen[canonical] {
+				changes = append(changes, URLChange{Field: path, Original: u.Url, Note: "removed duplicate of " + canonical})
+				continue
+			}
+			seen[canonical] = true
+
+			if canonical != u.Url {
+				changes = append(changes, URLChange{Field: path, Original: u.Url, Canonical: canonical})
+			}
+			kept = append(kept, URL{Url: canonical})
+		}
+		*field.ref = kept
+	}
+
+	return changes
+}
+
+// canonicalURLPatch builds a merge patch with YAML keys that writes the URL fields
+// touched by changes back into a project document
+func canonicalURLPatch(project *Project, changes []URLChange) map[string]interface{} {
+	changed := make(map[string]bool)
+	for _, change := range changes {
+		changed[strings.SplitN(change.Field, "[", 2)[0]] = true
+	}
+
+	patch := make(map[string]interface{})
+	social := make(map[string]interface{})
+	for _, field := range projectURLFields(project) {
+		if !changed[field.name] {
+			continue
+		}
+
+		urls := make([]interface{}, len(*field.ref))
+		for i, u := range *field.ref {
+			urls[i] = map[string]interface{}{"url": u.Url}
+		}
+
+		if key, ok := strings.CutPrefix(field.name, "social."); ok {
+			social[key] = urls
+		} else {
+			patch[field.name] = urls
+		}
+	}
+	if len(social) > 0 {
+		patch["social"] = social
+	}
+
+	return patch
+}
+
+// CanonicalizeURL returns the canonical form of a URL in the given project field
+// (e.g. "github" or "social.twitter"). URLs that cannot be parsed are returned
+// trimmed but otherwise untouched, so validation can report them.
+func CanonicalizeURL(field, raw string) string {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return trimmed
+	}
+
+	withScheme := trimmed
+	if !strings.Contains(withScheme, "://") {
+		withScheme = "https://" + withScheme
+	}
+
+	parsed, err := url.Parse(withScheme)
+	if err != nil || parsed.Hostname() == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return trimmed
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if alias, ok := hostAliases[host]; ok {
+		host = alias
+	}
+	segments := strings.FieldsFunc(parsed.EscapedPath(), func(r rune) bool { return r == '/' })
+
+	switch {
+	case host == "github.com" && field == "github" && len(segments) > 0:
+		// The directory lists organizations, not individual repositories, and
+		// github.com/orgs/<org> is the organization's own page
+		if segments[0] == "orgs" {
+			if len(segments) < 2 {
+				break
+			}
+			segments = segments[1:]
+		}
+		return "https://github.com/" + segments[0]
+
+	case host == "x.com" && len(segments) > 0:
+		return "https://x.com/" + strings.TrimPrefix(segments[0], "@")
+
+	case host == "t.me" && len(segments) > 0:
+		// t.me/s/<channel> is the web preview of t.me/<channel>
+		if segments[0] == "s" && len(segments) > 1 {
+			segments = segments[1:]
+		}
+		return "https://t.me/" + strings.Join(segments, "/")
+
+	case (host == "discord.gg" || host == "discord.com") && len(segments) > 0:
+		if segments[0] == "invite" && len(segments) > 1 {
+			return "https://discord.gg/" + segments[1]
+		}
+		if host == "discord.gg" {
+			return "https://discord.gg/" + segments[0]
+		}
+	}
+
+	scheme := parsed.Scheme
+	if sharedHosts[host] {
+		scheme = "https"
+	}
+
+	canonical := &url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		RawQuery: canonicalQuery(host, parsed.Query()),
+	}
+	if port := parsed.Port(); port != "" && !(scheme == "https" && port == "443") && !(scheme == "http" && port == "80") {
+		canonical.Host = host + ":" + port
+	}
+	if len(segments) > 0 {
+		canonical.RawPath = "/" + strings.Join(segments, "/")
+		if unescaped, err := url.PathUnescape(canonical.RawPath); err == nil {
+			canonical.Path = unescaped
+		} else {
+			return trimmed
+		}
+	} else if canonical.RawQuery != "" {
+		canonical.Path = "/"
+	}
+
+	return canonical.String()
+}
+
+// canonicalQuery drops tracking parameters, and every parameter on hosts whose
+// profile URLs are identified by their path alone
+func canonicalQuery(host string, query url.Values) string {
+	if pathOnlyHosts[host] {
+		return ""
+	}
+	for key := range query {
+		if trackingParams[key] || strings.HasPrefix(key, "utm_") {
+			query.Del(key)
+		}
+	}
+	return query.Encode()
+}
@@ -0,0 +1,97 @@
+package main
+
+import "testing"
+
+func TestCanonicalizeURL(t *testing.T) {
+	tests := []struct {
+		field, raw, want string
+	}{
+		// github field: reduced to the organization
+		{"github", "https://github.com/ethereum", "https://github.com/ethereum"},
+		{"github", "github.com/Ethereum/go-ethereum/tree/master?tab=1", "https://github.com/Ethereum"},
+		{"github", "https://www.github.com/orgs/ethereum", "https://github.com/ethereum"},
+		{"github", "https://github.com/orgs/ethereum/repositories", "https://github.com/ethereum"},
+		{"github", "https://github.com/orgs", "https://github.com/orgs"},
+		{"websites", "https://github.com/ethereum/go-ethereum", "https://github.com/ethereum/go-ethereum"},
+
+		// x.com
+		{"social.twitter", "https://twitter.com/@bebop_dex?s=20", "https://x.com/bebop_dex"},
+		{"social.twitter", "http://mobile.twitter.com/bebop_dex/status/1", "https://x.com/bebop_dex"},
+
+		// t.me
+		{"social.telegram", "http://telegram.me/s/bebop", "https://t.me/bebop"},
+		{"social.telegram", "t.me/bebop/12", "https://t.me/bebop/12"},
+
+		// Discord: only invites are rewritten
+		{"social.discord", "https://discord.com/invite/abc", "https://discord.gg/abc"},
+		{"social.discord", "discord.gg/abc/", "https://discord.gg/abc"},
+		{"social.discord", "https://discordapp.com/channels/1/2", "https://discord.com/channels/1/2"},
+
+		// Everything else
+		{"websites", "http://www.Example.com:80/path/?utm_source=x&id=2#top", "http://example.com/path?id=2"},
+		{"websites", "example.com/?ref=x", "https://example.com"},
+		{"websites", "https://example.com?b=1&fbclid=2", "https://example.com/?b=1"},
+		{"websites", "https://example.com:8443", "https://example.com:8443"},
+		{"social.medium", "http://medium.com/@bebop", "https://medium.com/@bebop"},
+		{"websites", "ftp://example.com", "ftp://example.com"},
+		{"websites", "  https://example.com  ", "https://example.com"},
+		{"websites", "  ", ""},
+	}
+
+	for _, tt := range tests {
+		if got := CanonicalizeURL(tt.field, tt.raw); got != tt.want {
+			t.Errorf("CanonicalizeURL(%q, %q) = %q, want %q", tt.field, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalizeProject(t *testing.T) {
+	project := &Project{
+		Name:     "bebop",
+		Websites: []URL{{Url: "https://www.bebop.xyz/"}, {Url: "https://bebop.xyz"}, {Url: ""}, {Url: " "}},
+		Github:   []URL{{Url: "https://github.com/orgs/bebop-dex"}},
+		Social:   &Social{Twitter: []URL{{Url: "https://x.com/bebop_dex"}}},
+	}
+
+	changes := CanonicalizeProject(project)
+
+	wantWebsites := []string{"https://bebop.xyz", "", ""}
+	if len(project.Websites) != len(wantWebsites) {
+		t.Fatalf("Websites = %+v, want %q", project.Websites, wantWebsites)
+	}
+	for i, want := range wantWebsites {
+		if project.Websites[i].Url != want {
+			t.Errorf("Websites[%d] = %q, want %q", i, project.Websites[i].Url, want)
+		}
+	}
+	if project.Github[0].Url != "https://github.com/bebop-dex" {
+		t.Errorf("Github = %+v", project.Github)
+	}
+
+	want := []URLChange{
+		{Field: "websites[0].url", Original: "https://www.bebop.xyz/", Canonical: "https://bebop.xyz"},
+		{Field: "websites[1].url", Original: "https://bebop.xyz", Note: "removed duplicate of https://bebop.xyz"},
+		{Field: "websites[3].url", Original: " "},
+		{Field: "github[0].url", Original: "https://github.com/orgs/bebop-dex", Canonical: "https://github.com/bebop-dex"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("changes[%d] = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+
+	// Both empty URLs are left for validation to report
+	project.DisplayName = "Bebop"
+	var empty []string
+	for _, problem := range (&ProjectValidator{}).Validate(project) {
+		if problem.Message == "URL cannot be empty" {
+			empty = append(empty, problem.Field)
+		}
+	}
+	if len(empty) != 2 || empty[0] != "websites[1].url" || empty[1] != "websites[2].url" {
+		t.Errorf("empty URL problems = %q, want websites[1] and websites[2]", empty)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v2"
+)
+
+// ValidationError describes a single problem with one field of a project
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ProjectValidator checks projects against the oss-directory JSON schema and our own rules
+type ProjectValidator struct {
+	schema *jsonschema.Schema // nil when no schema could be loaded
+}
+
+// projectSchemaFile is the name of the project schema inside the schema directory
+const projectSchemaFile = "project.json"
+
+// NewProjectValidator creates a validator using the project schema found in schemaDir.
+// If the schema cannot be loaded, the returned validator still applies our own rules
+// and the error explains why schema validation is disabled.
+func NewProjectValidator(schemaDir string) (*ProjectValidator, error) {
+	validator := &ProjectValidator{}
+
+	schemaPath := filepath.Join(schemaDir, projectSchemaFile)
+	if _, err := os.Stat(schemaPath); err != nil {
+		return validator, fmt.Errorf("project schema not available: %v", err)
+	}
+
+	// Referenced schemas (url.json, social-profile.json, ...) are resolved
+	// relative to the project schema file
+	compiler := jsonschema.NewCompiler()
+	schema, err := compiler.Compile(schemaPath)
+	if err != nil {
+		return validator, fmt.Errorf("error compiling project schema: %v", err)
+	}
+
+	validator.schema = schema
+	return validator, nil
+}
+
+// Validate returns every problem found in the project, or nil if it is valid
+func (v *ProjectValidator) Validate(project *Project) []ValidationError {
+	var problems []ValidationError
+
+	if v.schema != nil {
+		problems = append(problems, v.validateSchema(project)...)
+	}
+
+	problems = append(problems, validateProjectRules(project)...)
+
+	return dedupeValidationErrors(problems)
+}
+
+// validateSchema checks the project in the exact shape it will be written to disk
+func (v *ProjectValidator) validateSchema(project *Project) []ValidationError {
+	data, err := yaml.Marshal(project)
+	if err != nil {
+		return []ValidationError{{Field: "", Message: fmt.Sprintf("error marshalling YAML: %v", err)}}
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []ValidationError{{Field: "", Message: fmt.Sprintf("error parsing YAML: %v", err)}}
+	}
+
+	err = v.schema.Validate(jsonValue(doc))
+	if err == nil {
+		return nil
+	}
+
+	var schemaErr *jsonschema.ValidationError
+	if !errors.As(err, &schemaErr) {
+		return []ValidationError{{Field: "", Message: err.Error()}}
+	}
+
+	var problems []ValidationError
+	collectSchemaErrors(schemaErr, &problems)
+	return problems
+}
+
+// collectSchemaErrors flattens a schema validation error tree into its leaf errors
+func collectSchemaErrors(err *jsonschema.ValidationError, problems *[]ValidationError) {
+	if len(err.Causes) == 0 {
+		*problems = append(*problems, ValidationError{
+			Field:   fieldFromPointer(err.InstanceLocation),
+			Message: err.Message,
+		})
+		return
+	}
+
+	for _, cause := range err.Causes {
+		collectSchemaErrors(cause, problems)
+	}
+}
+
+// fieldFromPointer turns a JSON pointer like /websites/0/url into websites[0].url
+func fieldFromPointer(pointer string) string {
+	var field strings.Builder
+	for _, part := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		if _, err := strconv.Atoi(part); err == nil {
+			field.WriteString("[" + part + "]")
+			continue
+		}
+		if field.Len() > 0 {
+			field.WriteString(".")
+		}
+		field.WriteString(part)
+	}
+	return field.String()
+}
+
+// jsonValue converts decoded YAML into the types the JSON schema validator expects
+func jsonValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			converted[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(value))
+		for i, item := range value {
+			converted[i] = jsonValue(item)
+		}
+		return converted
+	}
+	return v
+}
+
+// validateProjectRules applies the checks the upstream schema does not express
+func validateProjectRules(project *Project) []ValidationError {
+	var problems []ValidationError
+
+	if err := ValidateProjectName(project.Name); err != nil {
+		problems = append(problems, ValidationError{Field: "name", Message: err.Error()})
+	}
+
+	if strings.TrimSpace(project.DisplayName) == "" {
+		problems = append(problems, ValidationError{Field: "display_name", Message: "display name is required"})
+	}
+
+	seen := make(map[string]string)
+	for _, field := range projectURLFields(project) {
+		for i, u := range field.urls {
+			location := fmt.Sprintf("%s[%d].url", field.name, i)
+
+			if strings.TrimSpace(u.Url) == "" {
+				problems = append(problems, ValidationError{Field: location, Message: "URL cannot be empty"})
+				continue
+			}
+
+			parsed, err := url.Parse(u.Url)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				problems = append(problems, ValidationError{Field: location, Message: fmt.Sprintf("%q is not a valid http(s) URL", u.Url)})
+				continue
+			}
+
+			key := strings.TrimSuffix(strings.ToLower(u.Url), "/")
+			if previous, ok := seen[key]; ok {
+				problems = append(problems, ValidationError{Field: location, Message: fmt.Sprintf("duplicate URL, already listed at %s", previous)})
+				continue
+			}
+			seen[key] = location
+		}
+	}
+
+	for i, address := range project.Blockchain {
+		location := fmt.Sprintf("blockchain[%d]", i)
+		if strings.TrimSpace(address.Address) == "" {
+			problems = append(problems, ValidationError{Field: location + ".address", Message: "address cannot be empty"})
+		}
+		if len(address.Networks) == 0 {
+			problems = append(problems, ValidationError{Field: location + ".networks", Message: "at least one network is required"})
+		}
+		if len(address.Tags) == 0 {
+			problems = append(problems, ValidationError{Field: location + ".tags", Message: "at least one tag is required"})
+		}
+	}
+
+	return problems
+}
+
+// urlField is a named list of URLs within a project
+type urlField struct {
+	name string
+	urls []URL
+	ref  *[]URL // The project field itself, for rewriting the list
+}
+
+// projectURLFields lists every URL-valued field of a project with its YAML path
+func projectURLFields(project *Project) []urlField {
+	fields := []urlField{
+		{"websites", project.Websites, &project.Websites},
+		{"github", project.Github, &project.Github},
+		{"npm", project.Npm, &project.Npm},
+		{"crates", project.Crates, &project.Crates},
+		{"pypi", project.Pypi, &project.Pypi},
+		{"go", project.Go, &project.Go},
+		{"open_collective", project.OpenCollective, &project.OpenCollective},
+		{"defillama", project.Defillama, &project.Defillama},
+	}
+
+	if social := project.Social; social != nil {
+		fields = append(fields,
+			urlField{"social.farcaster", social.Farcaster, &social.Farcaster},
+			urlField{"social.medium", social.Medium, &social.Medium},
+			urlField{"social.mirror", social.Mirror, &social.Mirror},
+			urlField{"social.telegram", social.Telegram, &social.Telegram},
+			urlField{"social.twitter", social.Twitter, &social.Twitter},
+			urlField{"social.discord", social.Discord, &social.Discord},
+			urlField{"social.youtube", social.Youtube, &social.Youtube},
+			urlField{"social.linkedin", social.Linkedin, &social.Linkedin},
+		)
+	}
+
+	return fields
+}
+
+// dedupeValidationErrors drops repeated problems reported by both the schema and our rules
+func dedupeValidationErrors(problems []ValidationError) []ValidationError {
+	seen := make(map[ValidationError]bool)
+	var unique []ValidationError
+	for _, problem := range problems {
+		if seen[problem] {
+			continue
+		}
+		seen[problem] = true
+		unique = append(unique, problem)
+	}
+	return unique
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testProjectSchema is a small stand-in for the upstream project schema, with a
+// referenced URL schema like the real one
+const testProjectSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["version", "name", "display_name"],
+  "additionalProperties": false,
+  "properties": {
+    "version": {"const": 7},
+    "name": {"type": "string"},
+    "display_name": {"type": "string"},
+    "description": {"type": "string", "maxLength": 20},
+    "websites": {"type": "array", "items": {"$ref": "url.json"}},
+    "github": {"type": "array", "items": {"$ref": "url.json"}}
+  }
+}`
+
+const testURLSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["url"],
+  "properties": {"url": {"type": "string", "pattern": "^https?://"}}
+}`
+
+func TestValidateProjectRules(t *testing.T) {
+	valid := func() *Project {
+		return &Project{
+			Version:     ProjectSchemaVersion,
+			Name:        "bebop",
+			DisplayName: "Bebop",
+			Websites:    []URL{{Url: "https://bebop.xyz"}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(p *Project)
+		want   []ValidationError
+	}{
+		{"valid", func(p *Project) {}, nil},
+		{"invalid name", func(p *Project) { p.Name = "Bebop DEX" }, []ValidationError{
+			{"name", `name must be lowercase letters, digits and single hyphens (e.g. "bebop-dex")`},
+		}},
+		{"missing display name", func(p *Project) { p.DisplayName = " " }, []ValidationError{
+			{"display_name", "display name is required"},
+		}},
+		{"empty URL", func(p *Project) { p.Github = []URL{{Url: ""}} }, []ValidationError{
+			{"github[0].url", "URL cannot be empty"},
+		}},
+		{"not http", func(p *Project) { p.Social = &Social{Twitter: []URL{{Url: "ftp://x.com/bebop"}}} }, []ValidationError{
+			{"social.twitter[0].url", `"ftp://x.com/bebop" is not a valid http(s) URL`},
+		}},
+		{"no host", func(p *Project) { p.Npm = []URL{{Url: "https://"}} }, []ValidationError{
+			{"npm[0].url", `"https://" is not a valid http(s) URL`},
+		}},
+		{"duplicate across fields", func(p *Project) { p.Defillama = []URL{{Url: "https://Bebop.xyz/"}} }, []ValidationError{
+			{"defillama[0].url", "duplicate URL, already listed at websites[0].url"},
+		}},
+		{"incomplete blockchain address", func(p *Project) { p.Blockchain = []BlockchainAddress{{Networks: []string{"mainnet"}}} }, []ValidationError{
+			{"blockchain[0].address", "address cannot be empty"},
+			{"blockchain[0].tags", "at least one tag is required"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := valid()
+			tt.modify(project)
+			if got := validateProjectRules(project); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateProjectRules() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldFromPointer(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		"/name":                 "name",
+		"/websites/0/url":       "websites[0].url",
+		"/social/twitter/1/url": "social.twitter[1].url",
+		"/a~1b/c~0d":            "a/b.c~d",
+	}
+
+	for pointer, want := range tests {
+		if got := fieldFromPointer(pointer); got != want {
+			t.Errorf("fieldFromPointer(%q) = %q, want %q", pointer, got, want)
+		}
+	}
+}
+
+func TestProjectValidatorSchema(t *testing.T) {
+	schemaDir := t.TempDir()
+	writeTestFile(t, schemaDir, projectSchemaFile, testProjectSchema)
+	writeTestFile(t, schemaDir, "url.json", testURLSchema)
+
+	validator, err := NewProjectValidator(schemaDir)
+	if err != nil {
+		t.Fatalf("NewProjectValidator() error = %v", err)
+	}
+
+	project := &Project{
+		Version:     6,
+		Name:        "bebop",
+		DisplayName: "Bebop",
+		Description: "A description that is far too long",
+		Websites:    []URL{{Url: "https://bebop.xyz"}},
+		Github:      []URL{{Url: "git://github.com/bebop-dex"}},
+	}
+	fields := make(map[string]bool)
+	for _, problem := range validator.Validate(project) {
+		fields[problem.Field] = true
+	}
+	for _, field := range []string{"version", "description", "github[0].url"} {
+		if !fields[field] {
+			t.Errorf("no problem reported for %s: %v", field, fields)
+		}
+	}
+
+	project.Version = ProjectSchemaVersion
+	project.Description = "A DEX"
+	project.Github = nil
+	if problems := validator.Validate(project); problems != nil {
+		t.Errorf("Validate() = %+v for a valid project", problems)
+	}
+
+	// Fields the schema does not allow are rejected
+	project.Npm = []URL{{Url: "https://www.npmjs.com/package/bebop"}}
+	if problems := validator.Validate(project); len(problems) != 1 || problems[0].Field != "" {
+		t.Errorf("Validate() = %+v, want one problem for the unknown field", problems)
+	}
+}
+
+func TestProjectValidatorWithoutSchema(t *testing.T) {
+	validator, err := NewProjectValidator(t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "project schema not available") {
+		t.Errorf("NewProjectValidator() error = %v, want the schema to be missing", err)
+	}
+	if validator == nil {
+		t.Fatal("NewProjectValidator() returned no validator")
+	}
+
+	// Our own rules still apply
+	problems := validator.Validate(&Project{Name: "bebop"})
+	if len(problems) != 1 || problems[0].Field != "display_name" {
+		t.Errorf("Validate() = %+v, want the missing display name", problems)
+	}
+
+	schemaDir := t.TempDir()
+	writeTestFile(t, schemaDir, projectSchemaFile, `{"type": `)
+	if _, err := NewProjectValidator(schemaDir); err == nil || !strings.Contains(err.Error(), "error compiling project schema") {
+		t.Errorf("NewProjectValidator() error = %v, want a compile error", err)
+	}
+}
+
+func TestLoadValidatorResolvesSchemaDir(t *testing.T) {
+	s := newTestServer(t, false)
+	s.config.SchemaDir = filepath.Join("schema", "v7")
+	writeTestFile(t, s.config.DirectoryPath, filepath.Join("schema", "v7", projectSchemaFile), testProjectSchema)
+	writeTestFile(t, s.config.DirectoryPath, filepath.Join("schema", "v7", "url.json"), testURLSchema)
+
+	s.loadValidator()
+	if s.validator == nil || s.validator.schema == nil {
+		t.Fatal("the schema relative to the checkout was not loaded")
+	}
+}